@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package clock contains abstraction of the current time, so all time
+// dependent behaviour (changing clusters, slow clusters, generated_at values)
+// can be controlled in tests.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is clock that returns real time
+type RealClock struct{}
+
+// Now returns the current real time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// MockClock is clock that returns time set explicitly. The time does not
+// change unless SetTime or Advance is called.
+type MockClock struct {
+	mutex sync.RWMutex
+	now   time.Time
+}
+
+// NewMockClock constructs mock clock set to given time
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+// Now returns the time the mock clock is set to
+func (clock *MockClock) Now() time.Time {
+	clock.mutex.RLock()
+	defer clock.mutex.RUnlock()
+	return clock.now
+}
+
+// SetTime sets the mock clock to given time
+func (clock *MockClock) SetTime(now time.Time) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = now
+}
+
+// Advance moves the mock clock forward by given duration and returns the new
+// time
+func (clock *MockClock) Advance(duration time.Duration) time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(duration)
+	return clock.now
+}
+
+// SkewedClock returns time of underlying clock shifted by given skew, so
+// clock of another machine that is ahead of (positive skew) or behind
+// (negative skew) can be simulated
+type SkewedClock struct {
+	Clock Clock
+	Skew  time.Duration
+}
+
+// NewSkewedClock constructs clock shifted by given skew from given clock
+func NewSkewedClock(clock Clock, skew time.Duration) SkewedClock {
+	return SkewedClock{Clock: clock, Skew: skew}
+}
+
+// Now returns current time of underlying clock shifted by the skew
+func (clock SkewedClock) Now() time.Time {
+	return clock.Clock.Now().Add(clock.Skew)
+}
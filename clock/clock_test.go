@@ -0,0 +1,54 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/clock"
+)
+
+// TestMockClock checks that mock clock returns the time it is set to
+func TestMockClock(t *testing.T) {
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockClock := clock.NewMockClock(start)
+	assert.Equal(t, start, mockClock.Now())
+
+	advanced := mockClock.Advance(30 * time.Minute)
+	assert.Equal(t, start.Add(30*time.Minute), advanced)
+	assert.Equal(t, advanced, mockClock.Now())
+
+	mockClock.SetTime(start)
+	assert.Equal(t, start, mockClock.Now())
+}
+
+func TestSkewedClock(t *testing.T) {
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockClock := clock.NewMockClock(start)
+
+	ahead := clock.NewSkewedClock(mockClock, 5*time.Minute)
+	assert.Equal(t, start.Add(5*time.Minute), ahead.Now())
+
+	behind := clock.NewSkewedClock(mockClock, -5*time.Minute)
+	assert.Equal(t, start.Add(-5*time.Minute), behind.Now())
+
+	mockClock.Advance(time.Hour)
+	assert.Equal(t, start.Add(time.Hour+5*time.Minute), ahead.Now())
+}
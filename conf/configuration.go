@@ -114,6 +114,12 @@ func LoadConfiguration(defaultConfigFile string) (ConfigStruct, error) {
 	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "__"))
 
 	err = viper.Unmarshal(&Config)
+	if err != nil {
+		return Config, err
+	}
+
+	// INSIGHTS_MOCK_* variables are applied on top of everything else
+	err = overrideFromEnv(ServerEnvPrefix, &Config.Server)
 	return Config, err
 }
 
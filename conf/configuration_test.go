@@ -15,3 +15,53 @@ limitations under the License.
 */
 
 package conf_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/conf"
+)
+
+// setEnv sets environment variable for the duration of test
+func setEnv(t *testing.T, name, value string) {
+	assert.NoError(t, os.Setenv(name, value))
+	t.Cleanup(func() {
+		assert.NoError(t, os.Unsetenv(name))
+	})
+}
+
+// TestServerConfigurationFromEnv checks that server options read from
+// configuration file are overridden by INSIGHTS_MOCK_* variables
+func TestServerConfigurationFromEnv(t *testing.T) {
+	setEnv(t, "INSIGHTS_RESULTS_AGGREGATOR_MOCK_CONFIG_FILE", "../config.toml")
+	setEnv(t, "INSIGHTS_MOCK_ADDRESS", ":9999")
+	setEnv(t, "INSIGHTS_MOCK_DEBUG", "true")
+	setEnv(t, "INSIGHTS_MOCK_MAX_CLUSTERS_PER_REQUEST", "42")
+	setEnv(t, "INSIGHTS_MOCK_DEFAULT_ORG_ID", "11789772")
+	setEnv(t, "INSIGHTS_MOCK_REQUEST_TIMEOUT", "5s")
+	setEnv(t, "INSIGHTS_MOCK_ALLOWED_ORIGINS", "http://a.example.com, http://b.example.com")
+	setEnv(t, "INSIGHTS_MOCK_RESPONSE_HEADERS", "X-Mock=yes")
+	setEnv(t, "INSIGHTS_MOCK_ORG_FAILURE_PROBABILITY", "1=0.5, 2=1")
+
+	config, err := conf.LoadConfiguration("config")
+	assert.NoError(t, err)
+
+	assert.Equal(t, ":9999", config.Server.Address)
+	assert.True(t, config.Server.Debug)
+	assert.Equal(t, 42, config.Server.MaxClustersPerRequest)
+	assert.EqualValues(t, 11789772, config.Server.DefaultOrgID)
+	assert.Equal(t, 5*time.Second, config.Server.RequestTimeout)
+	assert.Equal(t, []string{"http://a.example.com", "http://b.example.com"}, config.Server.AllowedOrigins)
+	assert.Equal(t, map[string]string{"X-Mock": "yes"}, config.Server.ResponseHeaders)
+	assert.Equal(t, map[string]float64{"1": 0.5, "2": 1}, config.Server.OrgFailureProbability)
+	// not overridden
+	assert.Equal(t, "/api/v1/", config.Server.APIPrefix)
+
+	setEnv(t, "INSIGHTS_MOCK_DEBUG", "maybe")
+	_, err = conf.LoadConfiguration("config")
+	assert.Error(t, err)
+}
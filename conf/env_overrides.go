@@ -0,0 +1,158 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ServerEnvPrefix is prefix of environment variables that override options
+// from the [server] section of configuration file. Name of the variable is
+// the prefix followed by option name in upper case, for example
+// INSIGHTS_MOCK_ADDRESS or INSIGHTS_MOCK_DEBUG.
+const ServerEnvPrefix = "INSIGHTS_MOCK_"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// overrideFromEnv overlays environment variables on top of configuration
+// structure. Each field with mapstructure tag can be overridden by variable
+// named by the prefix and upper-cased tag. Lists and maps are specified as
+// comma separated values, map items in name=value form.
+func overrideFromEnv(prefix string, config interface{}) error {
+	value := reflect.ValueOf(config).Elem()
+	structType := value.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		name := prefix + strings.ToUpper(tag)
+		envValue, found := os.LookupEnv(name)
+		if !found {
+			continue
+		}
+
+		err := setFieldFromString(value.Field(i), envValue)
+		if err != nil {
+			return fmt.Errorf("improper value of environment variable %s: %v", name, err)
+		}
+		log.Info().Str("variable", name).Msg("Configuration overridden from environment")
+	}
+
+	return nil
+}
+
+// setFieldFromString parses given string according to the type of field and
+// stores the result into the field
+func setFieldFromString(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(parsed)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %v", field.Type())
+		}
+		items := splitList(value)
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, item := range items {
+			slice.Index(i).SetString(item)
+		}
+		field.Set(slice)
+	case reflect.Map:
+		if field.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %v", field.Type())
+		}
+		m := reflect.MakeMap(field.Type())
+		for _, item := range splitList(value) {
+			parts := strings.SplitN(item, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("map item %q needs to be in name=value form", item)
+			}
+
+			key := reflect.New(field.Type().Key()).Elem()
+			key.SetString(strings.TrimSpace(parts[0]))
+
+			// values are parsed in the same way as fields
+			elem := reflect.New(field.Type().Elem()).Elem()
+			err := setFieldFromString(elem, strings.TrimSpace(parts[1]))
+			if err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		field.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+
+	return nil
+}
+
+// splitList splits comma separated list, empty items are skipped
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -41,6 +41,9 @@ const (
 	// ExitStatusOther represents other errors that might happen
 	ExitStatusOther
 
+	// ExitStatusValidationError is returned when data files are not valid
+	ExitStatusValidationError
+
 	defaultConfigFilename = "config"
 )
 
@@ -71,12 +74,17 @@ func startService(config conf.ConfigStruct) int {
 		return ExitStatusServerError
 	}
 
-	storage, err := storage.New(config.Paths.MockDataPath)
+	storage, err := storage.New(config.Paths.MockDataPath, server.StorageOptions(serverCfg))
 	if err != nil {
 		log.Error().Err(err).Msg("Storage init error")
 		return ExitStatusServerError
 	}
 
+	server.BuildVersion = BuildVersion
+	server.BuildTime = BuildTime
+	server.BuildBranch = BuildBranch
+	server.BuildCommit = BuildCommit
+
 	serverInstance = server.New(serverCfg, storage, groups)
 
 	err = serverInstance.Start()
@@ -85,6 +93,24 @@ func startService(config conf.ConfigStruct) int {
 		return ExitStatusServerError
 	}
 
+	// exit code might be requested via exit endpoint
+	return serverInstance.ExitCode
+}
+
+// validateData checks all report files without starting the service and
+// returns error code
+func validateData(config conf.ConfigStruct) int {
+	validationErrors := storage.Validate(config.Paths.MockDataPath)
+	for _, err := range validationErrors {
+		fmt.Println(err)
+	}
+
+	if len(validationErrors) > 0 {
+		fmt.Printf("%d improper data file(s) found\n", len(validationErrors))
+		return ExitStatusValidationError
+	}
+
+	fmt.Println("All report files are valid")
 	return ExitStatusOK
 }
 
@@ -124,6 +150,7 @@ The commands are:
     start-service                starts content service
     help     print-help          prints help
     config   print-config        prints current configuration set by files & env variables
+    validate validate-data       checks that all report files can be parsed, without starting the service
     version  print-version-info  prints version info
     authors  print-authors       prints authors
 
@@ -195,6 +222,8 @@ func handleCommand(config conf.ConfigStruct, command string) int {
 		return printHelp()
 	case "config", "print-config":
 		return printConfig(conf.Config)
+	case "validate", "validate-data":
+		return validateData(config)
 	case "version", "print-version-info":
 		return printVersionInfo()
 	case "authors", "print-authors":
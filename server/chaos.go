@@ -0,0 +1,138 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// defaultChaosMaxLatency is used when ChaosMaxLatency is not configured
+const defaultChaosMaxLatency = 5 * time.Second
+
+// requestIDHeader contains unique request identifier set by 3scale gateway
+const requestIDHeader = "x-rh-insights-request-id"
+
+// randomGenerator is random generator that can be used concurrently
+type randomGenerator struct {
+	mutex  sync.Mutex
+	random *rand.Rand
+}
+
+// float64 returns random number in range [0.0, 1.0)
+func (generator *randomGenerator) float64() float64 {
+	generator.mutex.Lock()
+	defer generator.mutex.Unlock()
+	return generator.random.Float64()
+}
+
+// duration returns random duration in range [0, max)
+func (generator *randomGenerator) duration(max time.Duration) time.Duration {
+	generator.mutex.Lock()
+	defer generator.mutex.Unlock()
+	return time.Duration(generator.random.Int63n(int64(max)))
+}
+
+// normFloat64 returns normally distributed number with mean 0 and standard
+// deviation 1
+func (generator *randomGenerator) normFloat64() float64 {
+	generator.mutex.Lock()
+	defer generator.mutex.Unlock()
+	return generator.random.NormFloat64()
+}
+
+// expFloat64 returns exponentially distributed number with mean 1
+func (generator *randomGenerator) expFloat64() float64 {
+	generator.mutex.Lock()
+	defer generator.mutex.Unlock()
+	return generator.random.ExpFloat64()
+}
+
+// isHealthEndpoint checks whether the request is made to an endpoint used by
+// health probes. These endpoints are never affected by chaos mode.
+func (server *HTTPServer) isHealthEndpoint(request *http.Request) bool {
+	return request.URL.Path == server.apiPrefix()+MainEndpoint
+}
+
+// newChaosMiddleware constructs middleware that randomly injects failures
+// (500 Internal Server Error) or latency spikes into processed requests
+func (server *HTTPServer) newChaosMiddleware() mux.MiddlewareFunc {
+	generator := &randomGenerator{
+		// disable "G404 (CWE-338): Use of weak random number generator"
+		// reproducibility is required there
+		// #nosec G404
+		random: rand.New(rand.NewSource(server.Config.ChaosSeed)),
+	}
+
+	maxLatency := server.Config.ChaosMaxLatency
+	if maxLatency <= 0 {
+		maxLatency = defaultChaosMaxLatency
+	}
+
+	log.Info().
+		Float64("probability", server.Config.ChaosProbability).
+		Int64("seed", server.Config.ChaosSeed).
+		Dur("max latency", maxLatency).
+		Msg("Chaos mode is enabled")
+
+	return func(nextHandler http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if server.isHealthEndpoint(r) || generator.float64() >= server.Config.ChaosProbability {
+					nextHandler.ServeHTTP(w, r)
+					return
+				}
+
+				requestID := r.Header.Get(requestIDHeader)
+
+				// the kind of fault is chosen randomly as well
+				if generator.float64() < 0.5 {
+					log.Warn().
+						Str("request ID", requestID).
+						Str("path", r.URL.Path).
+						Msg("Chaos mode: injecting internal server error")
+					writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+					return
+				}
+
+				delay := generator.duration(maxLatency)
+				log.Warn().
+					Str("request ID", requestID).
+					Str("path", r.URL.Path).
+					Dur("delay", delay).
+					Msg("Chaos mode: injecting latency spike")
+
+				// don't keep handler running when client gives up
+				timer := time.NewTimer(delay)
+				defer timer.Stop()
+				select {
+				case <-timer.C:
+					nextHandler.ServeHTTP(w, r)
+				case <-r.Context().Done():
+					log.Warn().
+						Str("request ID", requestID).
+						Str("path", r.URL.Path).
+						Msg("Chaos mode: request cancelled during latency spike")
+				}
+			})
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/clock"
+)
+
+// byParam is query parameter specifying how much the mock clock is advanced
+const byParam = "by"
+
+// advanceClock moves the mock clock forward by duration specified in query
+// parameter and returns the new current time (debug only)
+func (server *HTTPServer) advanceClock(writer http.ResponseWriter, request *http.Request) {
+	mockClock, ok := server.Clock.(*clock.MockClock)
+	if !ok {
+		// should not happen, the endpoint is registered for mock clock only
+		err := responses.SendBadRequest(writer, "mock clock is not active")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	by := request.URL.Query().Get(byParam)
+	duration, err := time.ParseDuration(by)
+	if err != nil || duration <= 0 {
+		log.Error().Str("by", by).Msg("Improper duration to advance clock by")
+		err := responses.SendBadRequest(writer, "by parameter needs to be positive duration, for example 30m")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	now := mockClock.Advance(duration)
+	log.Info().Dur("by", duration).Time("now", now).Msg("Mock clock advanced")
+
+	err = responses.SendOK(writer, responses.BuildOkResponseWithData("now", now.UTC().Format(time.RFC3339)))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
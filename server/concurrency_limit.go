@@ -0,0 +1,81 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// inFlightRequestsHeader contains number of requests being processed,
+	// it is sent in debug mode only
+	inFlightRequestsHeader = "X-In-Flight-Requests"
+
+	// tooManyRequestsMessage is returned when the limit of concurrent
+	// requests is reached
+	tooManyRequestsMessage = "Too many concurrent requests"
+
+	// concurrencyRetryAfter is number of seconds after which client should
+	// retry refused request
+	concurrencyRetryAfter = 1
+)
+
+// newConcurrencyLimitMiddleware constructs middleware that refuses requests
+// exceeding MaxConcurrentRequests with 503 Service Unavailable. Requests to
+// health endpoints are never refused and are not counted.
+func (server *HTTPServer) newConcurrencyLimitMiddleware() mux.MiddlewareFunc {
+	limit := server.Config.MaxConcurrentRequests
+	log.Info().Int("limit", limit).Msg("Number of concurrent requests is limited")
+
+	semaphore := make(chan struct{}, limit)
+	var inFlight int64
+
+	return func(nextHandler http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if server.isHealthEndpoint(r) {
+					nextHandler.ServeHTTP(w, r)
+					return
+				}
+
+				select {
+				case semaphore <- struct{}{}:
+				default:
+					log.Warn().Str("path", r.URL.Path).Msg("Limit of concurrent requests reached, request is refused")
+					w.Header().Set(retryAfterHeader, strconv.Itoa(concurrencyRetryAfter))
+					writeError(w, http.StatusServiceUnavailable, tooManyRequestsMessage)
+					return
+				}
+
+				count := atomic.AddInt64(&inFlight, 1)
+				defer func() {
+					atomic.AddInt64(&inFlight, -1)
+					<-semaphore
+				}()
+
+				if server.Config.Debug {
+					w.Header().Set(inFlightRequestsHeader, strconv.FormatInt(count, 10))
+				}
+				nextHandler.ServeHTTP(w, r)
+			})
+	}
+}
@@ -16,10 +16,138 @@ limitations under the License.
 
 package server
 
+import (
+	"time"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
 // Configuration represents configuration of REST API HTTP server
 type Configuration struct {
 	Address     string `mapstructure:"address" toml:"address"`
 	APIPrefix   string `mapstructure:"api_prefix" toml:"api_prefix"`
 	APISpecFile string `mapstructure:"api_spec_file" toml:"api_spec_file"`
 	Debug       bool   `mapstructure:"debug" toml:"debug"`
+	// StripPrefix is removed from path of incoming requests before they are
+	// routed, which is needed when the service runs behind proxy that adds
+	// the prefix to all paths. Requests without the prefix are routed as is.
+	StripPrefix string `mapstructure:"strip_prefix" toml:"strip_prefix"`
+	// TLS enables HTTPS; when the certificate and key files are not
+	// specified, a self-signed certificate is generated on startup
+	TLS         bool   `mapstructure:"tls" toml:"tls"`
+	TLSCertFile string `mapstructure:"tls_cert_file" toml:"tls_cert_file"`
+	TLSKeyFile  string `mapstructure:"tls_key_file" toml:"tls_key_file"`
+	// TLSMinVersion is the minimum TLS version accepted by the server (1.2
+	// or 1.3), TLS 1.2 is used when not set
+	TLSMinVersion string `mapstructure:"tls_min_version" toml:"tls_min_version"`
+	// TLSCipherSuites is list of allowed cipher suites (like
+	// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256) used by TLS 1.2, secure cipher
+	// suites selected by Go are used when the list is empty
+	TLSCipherSuites []string `mapstructure:"tls_cipher_suites" toml:"tls_cipher_suites"`
+	// MaxRequestBodySize is the maximum size of request body in bytes,
+	// DefaultMaxRequestBodySize is used when not set
+	MaxRequestBodySize int64 `mapstructure:"max_request_body_size" toml:"max_request_body_size"`
+	// MaxClustersPerRequest is the maximum number of clusters returned in
+	// one response, DefaultMaxClustersPerRequest is used when not set
+	MaxClustersPerRequest int `mapstructure:"max_clusters_per_request" toml:"max_clusters_per_request"`
+	// MaxClustersInOrgList is the maximum number of clusters returned in
+	// list of clusters for organization, the list is not limited when not set
+	MaxClustersInOrgList int `mapstructure:"max_clusters_in_org_list" toml:"max_clusters_in_org_list"`
+	// MaxConcurrentRequests is the maximum number of requests processed at
+	// the same time, other requests are refused with 503 Service
+	// Unavailable. Health endpoints are not limited. Zero disables the
+	// limit.
+	MaxConcurrentRequests int `mapstructure:"max_concurrent_requests" toml:"max_concurrent_requests"`
+	// ChaosProbability is the probability (0.0-1.0) that a request fails
+	// with 500 or is delayed by latency spike, zero disables chaos mode
+	ChaosProbability float64 `mapstructure:"chaos_probability" toml:"chaos_probability"`
+	// ChaosSeed is used to seed random generator so injected failures are
+	// reproducible
+	ChaosSeed int64 `mapstructure:"chaos_seed" toml:"chaos_seed"`
+	// ChaosMaxLatency is the upper limit for latency spikes
+	ChaosMaxLatency time.Duration `mapstructure:"chaos_max_latency" toml:"chaos_max_latency"`
+	// OrgFailureProbability maps organization ID to probability (0.0-1.0)
+	// that request for report of its cluster fails with 500, failures are
+	// injected for listed organizations only
+	OrgFailureProbability map[string]float64 `mapstructure:"org_failure_probability" toml:"org_failure_probability"`
+	// OrgFailureSeed is used to seed random generator so failures injected
+	// for organizations are reproducible
+	OrgFailureSeed int64 `mapstructure:"org_failure_seed" toml:"org_failure_seed"`
+	// RequestTimeout is the maximum time for processing one request, 503
+	// Service Unavailable is returned when exceeded. Streaming requests are
+	// not bound by the timeout. Zero disables the timeout.
+	RequestTimeout time.Duration `mapstructure:"request_timeout" toml:"request_timeout"`
+	// StreamPollInterval specifies how often the reports are checked for
+	// changes when streamed to clients
+	StreamPollInterval time.Duration `mapstructure:"stream_poll_interval" toml:"stream_poll_interval"`
+	// ValidateRequests enables validation of incoming requests against
+	// OpenAPI specification stored in APISpecFile
+	ValidateRequests bool `mapstructure:"validate_requests" toml:"validate_requests"`
+	// ResponseHeaders contains static headers added to all responses,
+	// headers set by handlers take precedence
+	ResponseHeaders map[string]string `mapstructure:"response_headers" toml:"response_headers"`
+	// AllowedOrigins is list of origins allowed to make cross-origin
+	// requests, CORS headers are not sent when the list is empty
+	AllowedOrigins []string `mapstructure:"allowed_origins" toml:"allowed_origins"`
+	// LatencyDistribution selects distribution of delay added to each
+	// response: constant, uniform, normal or exponential. Empty string
+	// disables the delay.
+	LatencyDistribution string `mapstructure:"latency_distribution" toml:"latency_distribution"`
+	// LatencyMean is the delay for constant distribution and mean value for
+	// normal and exponential distributions
+	LatencyMean time.Duration `mapstructure:"latency_mean" toml:"latency_mean"`
+	// LatencyStdDev is standard deviation for normal distribution
+	LatencyStdDev time.Duration `mapstructure:"latency_std_dev" toml:"latency_std_dev"`
+	// LatencyMin and LatencyMax specify range for uniform distribution
+	LatencyMin time.Duration `mapstructure:"latency_min" toml:"latency_min"`
+	LatencyMax time.Duration `mapstructure:"latency_max" toml:"latency_max"`
+	// LatencySeed is used to seed random generator so sampled delays are
+	// reproducible
+	LatencySeed int64 `mapstructure:"latency_seed" toml:"latency_seed"`
+	// SlowClusterDelay is the time after which report for "slow cluster"
+	// becomes ready, counted from the first request for the cluster
+	SlowClusterDelay time.Duration `mapstructure:"slow_cluster_delay" toml:"slow_cluster_delay"`
+	// BatchProcessingDelay is the time for which clusters POSTed to the
+	// clusters endpoint are reported as still processing, counted from the
+	// first request for the cluster. Zero disables the simulation.
+	BatchProcessingDelay time.Duration `mapstructure:"batch_processing_delay" toml:"batch_processing_delay"`
+	// DefaultOrgID is organization assumed by report/{cluster} endpoint, so
+	// organization permissions are checked even when the organization is
+	// not part of URL. Reports are searched globally when not set.
+	DefaultOrgID types.OrgID `mapstructure:"default_org_id" toml:"default_org_id"`
+	// FrozenTime (in RFC 3339 format) is used as current time in responses
+	// instead of real time, so generated_at values are deterministic
+	FrozenTime string `mapstructure:"frozen_time" toml:"frozen_time"`
+	// ClockSkew (positive or negative) is added to all generated
+	// timestamps to simulate clock of backend that is not synchronized
+	ClockSkew time.Duration `mapstructure:"clock_skew" toml:"clock_skew"`
+	// SyntheticClustersOrgID is organization for which SyntheticClustersCount
+	// synthetic clusters are generated in addition to its real clusters
+	SyntheticClustersOrgID types.OrgID `mapstructure:"synthetic_clusters_org_id" toml:"synthetic_clusters_org_id"`
+	SyntheticClustersCount int         `mapstructure:"synthetic_clusters_count" toml:"synthetic_clusters_count"`
+	// RuleTimestamps enables adding of deterministic created_at timestamps
+	// to rule hits in returned reports
+	RuleTimestamps bool `mapstructure:"rule_timestamps" toml:"rule_timestamps"`
+	// ClusterInfo enables adding of cluster version and managed flag (read
+	// from info_{cluster}.json file) to metadata of report for one cluster
+	ClusterInfo bool `mapstructure:"cluster_info" toml:"cluster_info"`
+	// NoContentForEmptyReports makes report for one cluster return 204 No
+	// Content instead of the report when the report contains no rule hits
+	NoContentForEmptyReports bool `mapstructure:"no_content_for_empty_reports" toml:"no_content_for_empty_reports"`
+	// ReportErrorDetails enables error_details with reasons of failures in
+	// responses with reports for several clusters
+	ReportErrorDetails bool `mapstructure:"report_error_details" toml:"report_error_details"`
+	// DeletedClusters are treated as if no report exists for them, even when
+	// report file is available
+	DeletedClusters []types.ClusterName `mapstructure:"deleted_clusters" toml:"deleted_clusters"`
+	// Tracing enables export of span for each request to OTLP collector
+	// listening on TracingEndpoint (OTLP/HTTP with protobuf encoding),
+	// DefaultTracingEndpoint is used when the endpoint is not set
+	Tracing         bool   `mapstructure:"tracing" toml:"tracing"`
+	TracingEndpoint string `mapstructure:"tracing_endpoint" toml:"tracing_endpoint"`
+	// DebugUsername and DebugPassword are credentials required (via HTTP
+	// basic auth) by debug endpoints, the endpoints are not protected when
+	// DebugUsername is not set
+	DebugUsername string `mapstructure:"debug_username" toml:"debug_username"`
+	DebugPassword string `mapstructure:"debug_password" toml:"debug_password"`
 }
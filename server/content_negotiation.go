@@ -0,0 +1,318 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-yaml/yaml"
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
+)
+
+const (
+	acceptHeader        = "Accept"
+	contentTypeHeader   = "Content-Type"
+	contentLengthHeader = "Content-Length"
+
+	acceptEncodingHeader  = "Accept-Encoding"
+	contentEncodingHeader = "Content-Encoding"
+	varyHeader            = "Vary"
+	gzipEncoding          = "gzip"
+
+	acceptLanguageHeader  = "Accept-Language"
+	contentLanguageHeader = "Content-Language"
+
+	// ContentTypeJSON represents MIME type for JSON format
+	ContentTypeJSON = "application/json; charset=utf-8"
+
+	// ContentTypeYAML represents MIME type for YAML format
+	ContentTypeYAML = "application/yaml"
+
+	// ContentTypeCSV represents MIME type for CSV format
+	ContentTypeCSV = "text/csv; charset=utf-8"
+
+	// ContentTypeNDJSON represents MIME type for newline delimited JSON
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	// ContentTypeMergePatch represents MIME type for JSON Merge Patch
+	ContentTypeMergePatch = "application/merge-patch+json"
+
+	// formatParam is query parameter that can be used instead of Accept
+	// header to select response format
+	formatParam = "format"
+
+	// profileParam is parameter of media type in Accept header that
+	// selects schema of report, legacyV1Profile selects v1 "flat" schema
+	profileParam    = "profile"
+	legacyV1Profile = "v1"
+)
+
+// yamlMediaTypes contains all media types that are understood as YAML
+var yamlMediaTypes = map[string]bool{
+	"application/yaml":   true,
+	"application/x-yaml": true,
+	"text/yaml":          true,
+	"text/x-yaml":        true,
+}
+
+// acceptsYAML checks whether YAML is the preferred format in Accept header.
+// Media types are checked in order in which they are specified, malformed
+// header is handled as if JSON is requested.
+func acceptsYAML(request *http.Request) bool {
+	accept := request.Header.Get(acceptHeader)
+	if accept == "" {
+		return false
+	}
+
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			log.Debug().Str("Accept", accept).Msg("Malformed Accept header")
+			return false
+		}
+		if yamlMediaTypes[mediaType] {
+			return true
+		}
+		if mediaType == "application/json" || mediaType == "*/*" {
+			return false
+		}
+	}
+
+	return false
+}
+
+// writeJSONOrYAML writes the JSON data to response as is or converted into
+// YAML when requested by client via Accept header. Content-Length is always
+// set, so for HEAD requests only headers are written.
+func writeJSONOrYAML(writer http.ResponseWriter, request *http.Request, data []byte) error {
+	body := data
+
+	if acceptsYAML(request) {
+		var decoded interface{}
+		err := json.Unmarshal(data, &decoded)
+		if err != nil {
+			return err
+		}
+
+		body, err = yaml.Marshal(decoded)
+		if err != nil {
+			return err
+		}
+
+		writer.Header().Set(contentTypeHeader, ContentTypeYAML)
+	} else {
+		writer.Header().Set(contentTypeHeader, ContentTypeJSON)
+	}
+
+	writer.Header().Set(contentLengthHeader, strconv.Itoa(len(body)))
+	if request.Method == http.MethodHead {
+		return nil
+	}
+
+	_, err := writer.Write(body)
+	return err
+}
+
+// acceptsGzip checks whether client accepts gzip content encoding
+func acceptsGzip(request *http.Request) bool {
+	for _, item := range strings.Split(request.Header.Get(acceptEncodingHeader), ",") {
+		// parameters have the same syntax as media type parameters
+		coding, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil || coding != gzipEncoding {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+// writeCompressedJSON writes already gzip-compressed JSON data to response.
+// Content-Length is always set, so for HEAD requests only headers are
+// written.
+func writeCompressedJSON(writer http.ResponseWriter, request *http.Request, compressed []byte) error {
+	writer.Header().Set(contentTypeHeader, ContentTypeJSON)
+	writer.Header().Set(contentEncodingHeader, gzipEncoding)
+	writer.Header().Add(varyHeader, acceptEncodingHeader)
+	writer.Header().Set(contentLengthHeader, strconv.Itoa(len(compressed)))
+	if request.Method == http.MethodHead {
+		return nil
+	}
+
+	_, err := writer.Write(compressed)
+	return err
+}
+
+// respondJSON writes response with given status and payload encoded into
+// (indented) JSON. Content-Type is always set, 500 Internal Server Error is
+// written when the payload can't be encoded.
+func respondJSON(writer http.ResponseWriter, status int, payload interface{}) {
+	body, err := json.MarshalIndent(payload, "", "\t")
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writer.Header().Set(contentTypeHeader, ContentTypeJSON)
+	writer.WriteHeader(status)
+
+	_, err = writer.Write(body)
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// respondJSONOrYAML writes response with given payload encoded into JSON, or
+// into YAML when requested by client via Accept header. 500 Internal Server
+// Error is written when the payload can't be encoded.
+func respondJSONOrYAML(writer http.ResponseWriter, request *http.Request, payload interface{}) {
+	body, err := json.MarshalIndent(payload, "", "\t")
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = writeJSONOrYAML(writer, request, body)
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// acceptsCSV checks whether CSV format is requested by client, either via
+// format query parameter or via Accept header
+func acceptsCSV(request *http.Request) bool {
+	if request.URL.Query().Get(formatParam) == "csv" {
+		return true
+	}
+
+	for _, item := range strings.Split(request.Header.Get(acceptHeader), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err == nil && mediaType == "text/csv" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptsNDJSON checks whether newline delimited JSON is requested by client
+// via Accept header
+func acceptsNDJSON(request *http.Request) bool {
+	for _, item := range strings.Split(request.Header.Get(acceptHeader), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err == nil && mediaType == ContentTypeNDJSON {
+			return true
+		}
+	}
+
+	return false
+}
+
+// acceptsLegacyV1 checks whether report in legacy v1 "flat" schema is
+// requested by client via profile parameter in Accept header, for example
+// "application/json; profile=v1"
+func acceptsLegacyV1(request *http.Request) bool {
+	for _, item := range strings.Split(request.Header.Get(acceptHeader), ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err == nil && params[profileParam] == legacyV1Profile {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeCSV writes header and rows in CSV format as an attachment with given
+// filename
+func writeCSV(writer http.ResponseWriter, filename string, header []string, rows [][]string) error {
+	writer.Header().Set(contentTypeHeader, ContentTypeCSV)
+	writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	csvWriter := csv.NewWriter(writer)
+	err := csvWriter.Write(header)
+	if err != nil {
+		return err
+	}
+
+	err = csvWriter.WriteAll(rows)
+	if err != nil {
+		return err
+	}
+
+	return csvWriter.Error()
+}
+
+// preferredLanguage returns language preferred by client via Accept-Language
+// header, only the default content language and given available languages
+// are taken into account. Language range like "de-AT" matches language "de"
+// as well. The default language is returned when no language matches.
+func preferredLanguage(request *http.Request, available []string) string {
+	preferred := storage.DefaultContentLanguage
+	preferredQuality := 0.0
+
+	for _, item := range strings.Split(request.Header.Get(acceptLanguageHeader), ",") {
+		// parameters have the same syntax as media type parameters
+		languageRange, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+		if quality <= preferredQuality {
+			continue
+		}
+
+		if language, found := matchLanguage(languageRange, available); found {
+			preferred, preferredQuality = language, quality
+		}
+	}
+
+	return preferred
+}
+
+// matchLanguage finds language matching given language range (in lower case)
+func matchLanguage(languageRange string, available []string) (string, bool) {
+	primary := strings.SplitN(languageRange, "-", 2)[0]
+	if languageRange == "*" || primary == storage.DefaultContentLanguage {
+		return storage.DefaultContentLanguage, true
+	}
+
+	for _, candidate := range []string{languageRange, primary} {
+		for _, language := range available {
+			if language == candidate {
+				return language, true
+			}
+		}
+	}
+	return "", false
+}
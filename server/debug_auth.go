@@ -0,0 +1,58 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	authenticateHeader = "WWW-Authenticate"
+	debugAuthRealm     = `Basic realm="debug", charset="UTF-8"`
+)
+
+// debugAuthMiddleware allows access to debug endpoints only for requests
+// with credentials matching DebugUsername and DebugPassword
+func (server *HTTPServer) debugAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		username, password, ok := request.BasicAuth()
+		if !ok || !server.checkDebugCredentials(username, password) {
+			log.Warn().Str("path", request.URL.Path).Msg("Unauthorized access to debug endpoint")
+
+			writer.Header().Set(authenticateHeader, debugAuthRealm)
+			err := responses.SendUnauthorized(writer, responses.BuildResponse("valid credentials are required"))
+			if err != nil {
+				log.Error().Err(err).Msg(responseDataError)
+			}
+			return
+		}
+
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// checkDebugCredentials compares given credentials with configured ones in
+// constant time
+func (server *HTTPServer) checkDebugCredentials(username, password string) bool {
+	usernameOK := subtle.ConstantTimeCompare([]byte(username), []byte(server.Config.DebugUsername)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(server.Config.DebugPassword)) == 1
+	return usernameOK && passwordOK
+}
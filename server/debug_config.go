@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/rs/zerolog/log"
+)
+
+// redactedValue replaces values of sensitive configuration options
+const redactedValue = "[redacted]"
+
+// sensitiveOptions contains configuration options whose values are never
+// exposed, options are identified by their names in configuration file
+var sensitiveOptions = map[string]bool{
+	"tls_cert_file":  true,
+	"tls_key_file":   true,
+	"debug_password": true,
+}
+
+// sanitizeConfiguration converts configuration into map with option names
+// used in configuration file as keys. Values of sensitive options are
+// redacted when set and durations are converted to strings like "1m30s".
+func sanitizeConfiguration(config Configuration) map[string]interface{} {
+	value := reflect.ValueOf(config)
+	configType := value.Type()
+
+	sanitized := make(map[string]interface{}, configType.NumField())
+	for i := 0; i < configType.NumField(); i++ {
+		name := configType.Field(i).Tag.Get("mapstructure")
+		if name == "" {
+			continue
+		}
+
+		field := value.Field(i)
+		switch {
+		case sensitiveOptions[name] && !field.IsZero():
+			sanitized[name] = redactedValue
+		case field.Type() == reflect.TypeOf(time.Duration(0)):
+			sanitized[name] = time.Duration(field.Int()).String()
+		default:
+			sanitized[name] = field.Interface()
+		}
+	}
+	return sanitized
+}
+
+// effectiveConfiguration returns configuration used by the running server
+// with sensitive options redacted (debug only)
+func (server *HTTPServer) effectiveConfiguration(writer http.ResponseWriter, _ *http.Request) {
+	config := sanitizeConfiguration(server.Config)
+
+	err := responses.SendOK(writer, responses.BuildOkResponseWithData("config", config))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
@@ -25,6 +25,9 @@ const (
 	// MainEndpoint defines suffix of the root endpoint
 	MainEndpoint = ""
 
+	// InfoEndpoint returns build information about the service
+	InfoEndpoint = "info"
+
 	// GroupsEndpoint defines suffix of the groups request endpoint
 	GroupsEndpoint = "groups"
 	// DeleteOrganizationsEndpoint deletes all {organizations}(comma separated array). DEBUG only
@@ -33,14 +36,49 @@ const (
 	DeleteClustersEndpoint = "clusters/{clusters}"
 	// OrganizationsEndpoint returns all organizations
 	OrganizationsEndpoint = "organizations"
+	// OrganizationsCountsEndpoint returns number of clusters for all known
+	// organizations
+	OrganizationsCountsEndpoint = "organizations/counts"
+	// OrganizationsAccessEndpoint returns all known organizations with flag
+	// whether they can be accessed
+	OrganizationsAccessEndpoint = "organizations/access"
 	// ClustersEndpoint returns reports for selected clusters
 	ClustersEndpoint = "clusters"
+	// ClustersByPatternEndpoint returns reports for clusters matching glob
+	// pattern specified by `glob` query parameter
+	ClustersByPatternEndpoint = "clusters/pattern"
 	// ClustersInOrgEndpoint returns reports for all clusters in selected organization
 	ClustersInOrgEndpoint = "clusters/{organization}"
 	// ReportEndpoint returns report for provided {organization} and {cluster}
 	ReportEndpoint = "report/{organization}/{cluster}"
+	// ReportSchemaEndpoint returns version of report schema and features
+	// supported by report endpoints
+	ReportSchemaEndpoint = "report/schema"
 	// ReportForClusterEndpoint returns report for provided {cluster} (w/o organization)
 	ReportForClusterEndpoint = "report/{cluster}"
+	// MultiReportsEndpoint returns reports for list of organization and
+	// cluster pairs
+	MultiReportsEndpoint = "reports/multi"
+	// ReportStreamEndpoint streams report for provided {cluster} as
+	// server-sent events whenever the report changes
+	ReportStreamEndpoint = "report/{cluster}/stream"
+	// ReportSummaryEndpoint returns summary of report for provided {cluster}
+	ReportSummaryEndpoint = "report/{cluster}/summary"
+	// RulesHitByClusterEndpoint returns identifiers of rules hit by provided
+	// {cluster}
+	RulesHitByClusterEndpoint = "cluster/{cluster}/rules"
+	// ReportHistoryEndpoint returns historical snapshots of report for
+	// provided {cluster}
+	ReportHistoryEndpoint = "cluster/{cluster}/history"
+	// RuleHitsByCategoryEndpoint returns number of rule hits for provided
+	// {cluster} in each rule category
+	RuleHitsByCategoryEndpoint = "cluster/{cluster}/categories"
+	// ReportSnapshotEndpoint returns snapshot of report for provided
+	// {cluster} taken at or nearest before {timestamp}
+	ReportSnapshotEndpoint = "cluster/{cluster}/history/{timestamp}"
+	// ReportsWebSocketEndpoint allows clients to subscribe to report changes
+	// for several clusters via WebSocket
+	ReportsWebSocketEndpoint = "reports/ws"
 	// LikeRuleEndpoint likes rule with {rule_id} for {cluster} using current user(from auth header)
 	LikeRuleEndpoint = "clusters/{cluster}/rules/{rule_id}/like"
 	// DislikeRuleEndpoint dislikes rule with {rule_id} for {cluster} using current user(from auth header)
@@ -58,14 +96,60 @@ const (
 	RuleGroupsEndpoint = "groups"
 	// ClustersForOrganizationEndpoint returns all clusters for {organization}
 	ClustersForOrganizationEndpoint = "organizations/{organization}/clusters"
+	// OrganizationStatsEndpoint returns number of clusters hitting each rule
+	// for {organization}
+	OrganizationStatsEndpoint = "organizations/{organization}/stats"
 	// DisableRuleForClusterEndpoint disables a rule for specified cluster
 	DisableRuleForClusterEndpoint = "clusters/{cluster}/rules/{rule_id}/disable"
 	// EnableRuleForClusterEndpoint re-enables a rule for specified cluster
 	EnableRuleForClusterEndpoint = "clusters/{cluster}/rules/{rule_id}/enable"
+	// DisabledRulesForClusterEndpoint returns all rules disabled for specified
+	// cluster
+	DisabledRulesForClusterEndpoint = "clusters/{cluster}/rules/disabled"
+	// RulesByTagEndpoint returns all rules bearing specified {tag}
+	RulesByTagEndpoint = "content/tags/{tag}"
+	// RuleFrequencyEndpoint returns number of clusters hit by each rule
+	RuleFrequencyEndpoint = "content/frequency"
+	// RuleContentEndpoint returns content of all error keys of rule, texts
+	// are localized according to Accept-Language header
+	RuleContentEndpoint = "content/{rule_id}"
 	// RuleClusterDetailEndpoint should return a list of all the clusters IDs affected by this rule
 	RuleClusterDetailEndpoint = "rule/{rule_selector}/clusters_detail/"
 	// MetricsEndpoint returns prometheus metrics
 	MetricsEndpoint = "metrics"
+	// ExitEndpoint stops the service, the exit code can be specified by
+	// optional `code` query parameter. DEBUG only
+	ExitEndpoint = "exit"
+	// ChangingClusterEndpoint returns report variant currently served for
+	// "changing cluster". DEBUG only
+	ChangingClusterEndpoint = "debug/changing/{cluster}"
+	// DumpEndpoint returns summary of data held in storage. DEBUG only
+	DumpEndpoint = "debug/dump"
+	// LoadedFilesEndpoint returns list of loaded report files. DEBUG only
+	LoadedFilesEndpoint = "debug/files"
+	// ReloadEndpoint re-reads all data files. DEBUG only
+	ReloadEndpoint = "debug/reload"
+	// DebugReportEndpoint allows to modify or delete report for {cluster}.
+	// DEBUG only
+	DebugReportEndpoint = "debug/report/{cluster}"
+	// AdvanceClockEndpoint moves the mock clock forward by duration specified
+	// by `by` query parameter. DEBUG only, available for mock clock only
+	AdvanceClockEndpoint = "debug/clock/advance"
+	// ResetEndpoint resets state of "slow clusters", denied organizations,
+	// deleted clusters and deleted reports. DEBUG only
+	ResetEndpoint = "debug/reset"
+	// DenyOrganizationEndpoint denies or allows access to {organization},
+	// DEBUG only
+	DenyOrganizationEndpoint = "debug/org/{organization}/deny"
+	// DeleteClusterEndpoint marks {cluster} as deleted or restores it,
+	// DEBUG only
+	DeleteClusterEndpoint = "debug/cluster/{cluster}/deleted"
+	// ConfigEndpoint returns effective configuration of the server with
+	// sensitive options redacted, DEBUG only
+	ConfigEndpoint = "debug/config"
+	// ReportDiffEndpoint returns rule hits present in report of one of two
+	// clusters specified by `a` and `b` query parameters. DEBUG only
+	ReportDiffEndpoint = "debug/diff"
 )
 
 // MakeURLToEndpoint creates URL to endpoint, use constants from file endpoints.go
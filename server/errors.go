@@ -15,6 +15,9 @@
 package server
 
 import (
+	"net/http"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
 	"github.com/rs/zerolog/log"
 )
 
@@ -26,6 +29,20 @@ type AuthenticationError struct {
 	errString string
 }
 
+// ErrorResponse represents body of error response in the same format as
+// used by Insights Results Aggregator, the status contains error message
+type ErrorResponse struct {
+	Status string `json:"status"`
+}
+
+// writeError writes error response with given HTTP code and message
+func writeError(writer http.ResponseWriter, code int, message string) {
+	err := responses.Send(code, writer, ErrorResponse{Status: message})
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
 // handleServerError handles separate server errors and sends appropriate responses
 func handleServerError(err error) {
 	log.Error().Err(err).Msg("handleServerError()")
@@ -15,3 +15,28 @@ limitations under the License.
 */
 
 package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/server"
+)
+
+// TestFailureClusterErrorBody checks that 5xx response for failure cluster
+// has the same body as error responses sent by Insights Results Aggregator
+func TestFailureClusterErrorBody(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/ffffffff-ffff-ffff-ffff-000000000503", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Contains(t, response.Header().Get("Content-Type"), "application/json")
+
+	var body server.ErrorResponse
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusText(http.StatusServiceUnavailable), body.Status)
+}
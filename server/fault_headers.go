@@ -0,0 +1,115 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/rs/zerolog/log"
+)
+
+// Request headers that inject failure or latency into one request. They are
+// handled in debug mode only.
+const (
+	// mockStatusHeader contains HTTP status code returned instead of
+	// calling the handler
+	mockStatusHeader = "x-mock-status"
+	// mockDelayHeader contains delay (in milliseconds) before the request
+	// is processed
+	mockDelayHeader = "x-mock-delay"
+)
+
+// range of status codes that can be requested by mockStatusHeader
+const (
+	minMockStatus = 200
+	maxMockStatus = 599
+)
+
+// injectRequestedFaults is middleware that delays the request and/or
+// responds with status code as requested by x-mock-delay and x-mock-status
+// headers
+func (server *HTTPServer) injectRequestedFaults(nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		delayHeader := request.Header.Get(mockDelayHeader)
+		statusHeader := request.Header.Get(mockStatusHeader)
+		if delayHeader == "" && statusHeader == "" {
+			nextHandler.ServeHTTP(writer, request)
+			return
+		}
+
+		delay, status, err := parseRequestedFaults(delayHeader, statusHeader)
+		if err != nil {
+			log.Error().Err(err).Msg("Improper fault injection headers")
+			err := responses.SendBadRequest(writer, err.Error())
+			if err != nil {
+				log.Error().Err(err).Msg(responseDataError)
+			}
+			return
+		}
+
+		if delay > 0 {
+			log.Info().Str("path", request.URL.Path).Dur("delay", delay).Msg("Injecting requested delay")
+			select {
+			case <-time.After(delay):
+			case <-request.Context().Done():
+				// client is gone or timeout expired
+				return
+			}
+		}
+
+		if status == 0 {
+			nextHandler.ServeHTTP(writer, request)
+			return
+		}
+
+		log.Info().Str("path", request.URL.Path).Int("status", status).Msg("Injecting requested status")
+		if status >= http.StatusBadRequest {
+			writeError(writer, status, http.StatusText(status))
+			return
+		}
+		writer.WriteHeader(status)
+	})
+}
+
+// parseRequestedFaults parses values of x-mock-delay and x-mock-status
+// headers, zero is returned for header that is not set
+func parseRequestedFaults(delayHeader, statusHeader string) (time.Duration, int, error) {
+	var delay time.Duration
+	if delayHeader != "" {
+		milliseconds, err := strconv.Atoi(delayHeader)
+		if err != nil || milliseconds < 0 {
+			return 0, 0, fmt.Errorf("%s header needs to be non-negative number of milliseconds", mockDelayHeader)
+		}
+		delay = time.Duration(milliseconds) * time.Millisecond
+	}
+
+	var status int
+	if statusHeader != "" {
+		var err error
+		status, err = strconv.Atoi(statusHeader)
+		if err != nil || status < minMockStatus || status > maxMockStatus {
+			return 0, 0, fmt.Errorf("%s header needs to be HTTP status code in range %d..%d",
+				mockStatusHeader, minMockStatus, maxMockStatus)
+		}
+	}
+
+	return delay, status, nil
+}
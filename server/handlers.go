@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -32,6 +33,8 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/RedHatInsights/insights-results-aggregator-mock/data"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/groups"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
 	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
 )
 
@@ -39,6 +42,67 @@ const failureClusterIDPrefix = "ffffffff-ffff-ffff-ffff-"
 
 const unableToReadReportErrorMessage = "Unable to read report for cluster"
 
+// inflateParam is query parameter that specifies number of synthetic rule
+// hits added into report
+const inflateParam = "inflate"
+
+// query parameters used to sort list of clusters
+const (
+	sortParam  = "sort"
+	orderParam = "order"
+	sortByHits = "hits"
+	orderAsc   = "asc"
+	orderDesc  = "desc"
+)
+
+// headers set when list of clusters for organization is truncated, they are
+// set for all representations (JSON and CSV) of the list
+const (
+	truncatedHeader  = "X-Truncated"
+	totalCountHeader = "X-Total-Count"
+)
+
+// minRiskParam is query parameter that specifies the lowest total risk of
+// rule hits returned in report
+const minRiskParam = "minRisk"
+
+// orderByParam is query parameter that selects ordering of rule hits in
+// report
+const orderByParam = "order_by"
+
+// globParam is query parameter with pattern for cluster names
+const globParam = "glob"
+
+// fractionParam is query parameter that specifies fraction (0.0-1.0) of rule
+// hits returned in report
+const fractionParam = "fraction"
+
+// emptyParam is query parameter that requests report without rule hits
+const emptyParam = "empty"
+
+// changedSinceParam is query parameter with time (in RFC 3339 format) used to
+// select clusters whose reports have been changed after that time
+const changedSinceParam = "changed_since"
+
+// tagParam is name of URL variable with rule tag and ignoreCaseParam is
+// query parameter that selects case-insensitive comparison of tags
+const (
+	tagParam        = "tag"
+	ignoreCaseParam = "ignoreCase"
+)
+
+// malformedRequestBodyMessage is returned to client when request body can
+// not be decoded
+const malformedRequestBodyMessage = "malformed request body"
+
+// invalidRequestBodyMessage is returned to client together with list of
+// validation errors when request body does not contain expected data
+const invalidRequestBodyMessage = "invalid request body"
+
+// requestBodyTooLargeMessage is returned to client when request body exceeds
+// the configured limit
+const requestBodyTooLargeMessage = "request body too large"
+
 // readOrganizationID retrieves organization id from request
 // if it's not possible, it writes http error to the writer and returns error
 func readOrganizationID(writer http.ResponseWriter, request *http.Request) (types.OrgID, error) {
@@ -70,143 +134,1117 @@ func readClusterName(writer http.ResponseWriter, request *http.Request) (types.C
 		return "", err
 	}
 
+	validClusterName, err := storage.ValidateClusterName(clusterName)
+	if err != nil {
+		log.Error().Err(err).Msg("Improper cluster name")
+		err := responses.SendBadRequest(writer, err.Error())
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return "", err
+	}
+	return validClusterName, nil
+}
+
+// readClusterNameOrDisplayName retrieves cluster name from request, cluster
+// can be specified by its UUID or by its display name. If it's not
+// possible, it writes http error to the writer and returns error.
+func (server *HTTPServer) readClusterNameOrDisplayName(writer http.ResponseWriter, request *http.Request) (types.ClusterName, error) {
+	nameOrUUID, err := getRouterParam(request, "cluster")
+	if err != nil {
+		return "", err
+	}
+
+	clusterName, err := server.Storage.ResolveClusterName(nameOrUUID)
+	if err != nil {
+		log.Error().Err(err).Msg("Improper cluster name")
+		err := responses.SendBadRequest(writer, err.Error())
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return "", err
+	}
+	return clusterName, nil
+}
+
+// getRouterParam retrieves parameter from URL like `/organization/{org_id}`
+func getRouterParam(request *http.Request, paramName string) (string, error) {
+	value, found := mux.Vars(request)[paramName]
+	if !found {
+		return "", errors.New("Missing param")
+	}
+
+	return value, nil
+}
+
+// getRouterPositiveIntParam retrieves parameter from URL like `/organization/{org_id}`
+// and check it for being valid and positive integer, otherwise returns error
+func getRouterPositiveIntParam(request *http.Request, paramName string) (uint64, error) {
+	value, err := getRouterParam(request, paramName)
+	if err != nil {
+		return 0, err
+	}
+
+	uintValue, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	if uintValue == 0 {
+		return 0, err
+	}
+
+	return uintValue, nil
+}
+
+// decodeJSONBody decodes JSON request body into dst. Body size is limited by
+// limitRequestBodySize middleware. If it's not possible to decode the body,
+// error response is written to the writer and error is returned.
+func (server *HTTPServer) decodeJSONBody(writer http.ResponseWriter, request *http.Request, dst interface{}) error {
+	err := json.NewDecoder(request.Body).Decode(dst)
+	if err == nil {
+		return nil
+	}
+
+	log.Error().Err(err).Msg("Unable to decode request body")
+
+	var sendErr error
+	if isRequestBodyTooLarge(err) {
+		sendErr = responses.Send(http.StatusRequestEntityTooLarge, writer, responses.BuildResponse(requestBodyTooLargeMessage))
+	} else {
+		sendErr = responses.SendBadRequest(writer, malformedRequestBodyMessage)
+	}
+	if sendErr != nil {
+		log.Error().Err(sendErr).Msg(responseDataError)
+	}
+
+	return err
+}
+
+// mainEndpoint will handle the requests for / endpoint
+func (server *HTTPServer) mainEndpoint(writer http.ResponseWriter, _ *http.Request) {
+	err := responses.SendOK(writer, responses.BuildOkResponse())
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// exitEndpoint will handle the requests for exit endpoint (debug only). The
+// response is sent and flushed before the server is stopped.
+func (server *HTTPServer) exitEndpoint(writer http.ResponseWriter, request *http.Request) {
+	code := 0
+
+	codeParam := request.URL.Query().Get("code")
+	if codeParam != "" {
+		var err error
+		code, err = strconv.Atoi(codeParam)
+		if err != nil || code < 0 || code > 255 {
+			log.Error().Str("code", codeParam).Msg("Improper exit code")
+			err := responses.SendBadRequest(writer, "exit code needs to be an integer in range 0..255")
+			if err != nil {
+				log.Error().Err(err).Msg(responseDataError)
+			}
+			return
+		}
+	}
+
+	err := responses.SendOK(writer, responses.BuildOkResponseWithData("exit_code", code))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+
+	// make sure the client gets the response before the server is stopped
+	if flusher, ok := writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	// shutdown waits for active requests (including this one) to finish
+	go server.exit(code)
+}
+
+// serveAPISpecFile serves an OpenAPI specifications file specified in config file
+func (server *HTTPServer) serveAPISpecFile(writer http.ResponseWriter, request *http.Request) {
+	absPath, err := filepath.Abs(server.Config.APISpecFile)
+	if err != nil {
+		const message = "Error creating absolute path of OpenAPI spec file"
+		log.Error().Err(err).Msg(message)
+		handleServerError(err)
+		return
+	}
+
+	http.ServeFile(writer, request, absPath)
+}
+
+// GroupWithRuleCount represents rule group together with number of rules
+// that belong to the group
+type GroupWithRuleCount struct {
+	groups.Group
+	RuleCount int `json:"rule_count"`
+}
+
+// countRulesInGroup returns number of rules that have at least one tag
+// belonging to given group
+func countRulesInGroup(group groups.Group, rules []types.RuleWithContent) int {
+	groupTags := make(map[string]bool)
+	for _, tag := range group.Tags {
+		groupTags[tag] = true
+	}
+
+	count := 0
+	for _, rule := range rules {
+		for _, tag := range rule.Tags {
+			if groupTags[tag] {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// listOfGroupsWithCounts returns the list of defined groups, each group
+// contains number of rules belonging to it
+func (server *HTTPServer) listOfGroupsWithCounts(writer http.ResponseWriter, request *http.Request) {
+	rules, err := server.Storage.ListOfRulesWithContent()
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to get list of rules")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	groupsWithCounts := make([]GroupWithRuleCount, 0, len(server.Groups))
+	for _, group := range server.Groups {
+		groupsWithCounts = append(groupsWithCounts, GroupWithRuleCount{
+			Group:     group,
+			RuleCount: countRulesInGroup(group, rules),
+		})
+	}
+
+	// stable output
+	sort.Slice(groupsWithCounts, func(i, j int) bool {
+		return groupsWithCounts[i].Name < groupsWithCounts[j].Name
+	})
+
+	respondJSONOrYAML(writer, request, responses.BuildOkResponseWithData("groups", groupsWithCounts))
+}
+
+// listOfRulesWithTag returns all rules bearing tag specified in URL, tags
+// are compared case-insensitively when ignoreCase query parameter is set
+func (server *HTTPServer) listOfRulesWithTag(writer http.ResponseWriter, request *http.Request) {
+	tag := mux.Vars(request)[tagParam]
+	ignoreCase := request.URL.Query().Get(ignoreCaseParam) == "true"
+
+	rules, err := server.Storage.RulesWithTag(tag, ignoreCase)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to get list of rules")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rules = server.localizeRules(writer, request, rules)
+
+	response := responses.BuildOkResponseWithData("rules", rules)
+	response["count"] = len(rules)
+	respondJSON(writer, http.StatusOK, response)
+}
+
+// readRuleContent returns content of all error keys of rule specified in
+// URL, texts are translated into language preferred by client
+func (server *HTTPServer) readRuleContent(writer http.ResponseWriter, request *http.Request) {
+	ruleID, err := readRuleID(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	rules, err := server.Storage.RuleContent(ruleID)
+	if _, notFound := err.(*types.ItemNotFoundError); notFound {
+		err := responses.SendNotFound(writer, err.Error())
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to get rule content")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	respondJSONOrYAML(writer, request, responses.BuildOkResponseWithData("content", server.localizeRules(writer, request, rules)))
+}
+
+// localizeRules translates texts of given rules into language preferred by
+// client, the language actually used is specified in Content-Language
+// header
+func (server *HTTPServer) localizeRules(writer http.ResponseWriter, request *http.Request, rules []types.RuleWithContent) []types.RuleWithContent {
+	language := preferredLanguage(request, server.Storage.RuleContentLanguages())
+	writer.Header().Set(contentLanguageHeader, language)
+	writer.Header().Add(varyHeader, acceptLanguageHeader)
+
+	if language == storage.DefaultContentLanguage {
+		return rules
+	}
+	return server.Storage.LocalizeRules(rules, language)
+}
+
+// limitParam is query parameter that specifies maximum number of returned
+// items
+const limitParam = "limit"
+
+// readLimit retrieves optional limit of returned items from request, zero
+// is returned when the limit is not specified. If the limit is not valid, it
+// writes http error to the writer and returns error.
+func readLimit(writer http.ResponseWriter, request *http.Request) (int, error) {
+	limitStr := request.URL.Query().Get(limitParam)
+	if limitStr == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 0 {
+		log.Error().Str("limit", limitStr).Msg("Improper limit")
+		err := responses.SendBadRequest(writer, "limit parameter needs to be a non-negative integer")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return 0, errors.New("improper limit")
+	}
+	return limit, nil
+}
+
+// ruleHitFrequency returns number of clusters hit by each rule, the most
+// frequent rules first
+func (server *HTTPServer) ruleHitFrequency(writer http.ResponseWriter, request *http.Request) {
+	limit, err := readLimit(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	frequencies := server.Storage.RuleHitFrequency(limit)
+
+	respondJSON(writer, http.StatusOK, responses.BuildOkResponseWithData("rules", frequencies))
+}
+
+// listOfGroups returns the list of defined groups
+func (server *HTTPServer) listOfGroups(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Query().Get("withCounts") == "true" {
+		server.listOfGroupsWithCounts(writer, request)
+		return
+	}
+
+	absPath, err := filepath.Abs(server.Config.APISpecFile)
+	if err != nil {
+		log.Error().Err(err)
+		handleServerError(err)
+		return
+	}
+
+	http.ServeFile(writer, request, absPath)
+}
+
+func (server *HTTPServer) listOfOrganizations(writer http.ResponseWriter, _ *http.Request) {
+	organizations, err := server.Storage.ListOfOrgs()
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to get list of organizations")
+		return
+	}
+	err = responses.SendOK(writer, responses.BuildOkResponseWithData("organizations", organizations))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// onParam is query parameter that switches denying of access to
+// organization or deletion of cluster on or off
+const onParam = "on"
+
+// denyOrganization denies or allows access to given organization at runtime
+// (debug only)
+func (server *HTTPServer) denyOrganization(writer http.ResponseWriter, request *http.Request) {
+	organizationID, err := readOrganizationID(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	denied, err := strconv.ParseBool(request.URL.Query().Get(onParam))
+	if err != nil {
+		log.Error().Str("on", request.URL.Query().Get(onParam)).Msg("Improper flag for denying organization")
+		err := responses.SendBadRequest(writer, "on parameter needs to be a boolean value")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	server.Storage.SetOrganizationDenied(organizationID, denied)
+	log.Info().Uint32("org", uint32(organizationID)).Bool("denied", denied).Msg("Access to organization changed")
+
+	response := responses.BuildOkResponse()
+	response["org_id"] = organizationID
+	response["denied"] = denied
+	err = responses.SendOK(writer, response)
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// deleteCluster marks given cluster as deleted or restores it at runtime
+// (debug only)
+func (server *HTTPServer) deleteCluster(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	deleted, err := strconv.ParseBool(request.URL.Query().Get(onParam))
+	if err != nil {
+		log.Error().Str("on", request.URL.Query().Get(onParam)).Msg("Improper flag for deleting cluster")
+		err := responses.SendBadRequest(writer, "on parameter needs to be a boolean value")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	server.Storage.SetClusterDeleted(clusterName, deleted)
+	log.Info().Str("cluster", string(clusterName)).Bool("deleted", deleted).Msg("Cluster deletion changed")
+
+	response := responses.BuildOkResponse()
+	response["cluster"] = clusterName
+	response["deleted"] = deleted
+	err = responses.SendOK(writer, response)
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// deniedOrganization is reported instead of number of clusters for
+// organization that can't be accessed
+const deniedOrganization = "denied"
+
+// countsOfClustersForOrganizations returns number of clusters for each known
+// organization
+func (server *HTTPServer) countsOfClustersForOrganizations(writer http.ResponseWriter, request *http.Request) {
+	counts := make(map[types.OrgID]interface{})
+
+	for _, orgID := range server.Storage.KnownOrganizations() {
+		clusters, err := server.Storage.ListOfClustersForOrg(orgID)
+		if err != nil {
+			counts[orgID] = deniedOrganization
+			continue
+		}
+		counts[orgID] = len(clusters)
+	}
+
+	// map keys are sorted by JSON encoder, so the output is stable
+	err := responses.SendOK(writer, responses.BuildOkResponseWithData("counts", counts))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// OrganizationAccess represents whether one organization can be accessed
+type OrganizationAccess struct {
+	OrgID   types.OrgID `json:"org_id"`
+	Allowed bool        `json:"allowed"`
+}
+
+// accessToOrganizations returns all known organizations, sorted by their
+// IDs, with flag whether the organization can be accessed
+func (server *HTTPServer) accessToOrganizations(writer http.ResponseWriter, request *http.Request) {
+	known := server.Storage.KnownOrganizations()
+	access := make([]OrganizationAccess, 0, len(known))
+
+	for _, orgID := range known {
+		// the same check as for list of clusters for organization
+		_, err := server.Storage.ListOfClustersForOrg(orgID)
+		access = append(access, OrganizationAccess{
+			OrgID:   orgID,
+			Allowed: err == nil,
+		})
+	}
+
+	err := responses.SendOK(writer, responses.BuildOkResponseWithData("organizations", access))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+func (server *HTTPServer) listOfClustersForOrganization(writer http.ResponseWriter, request *http.Request) {
+	organizationID, err := readOrganizationID(writer, request)
+
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	clusters, err := server.Storage.ListOfClustersForOrg(organizationID)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to get list of clusters")
+		handleServerError(err)
+		err := responses.SendForbidden(writer, err.Error())
+		if err != nil {
+			log.Error().Err(err).Msg("Unable send forbidden response")
+		}
+		return
+	}
+
+	// unknown organization is distinguished from organization without clusters
+	if !server.Storage.IsKnownOrganization(organizationID) {
+		log.Error().Uint32("org", uint32(organizationID)).Msg("Unknown organization")
+		err := responses.SendNotFound(writer, fmt.Sprintf("organization %d not found", organizationID))
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	changedSince := request.URL.Query().Get(changedSinceParam)
+	if changedSince != "" {
+		since, err := time.Parse(time.RFC3339, changedSince)
+		if err != nil {
+			log.Error().Str("changed_since", changedSince).Msg("Improper time specification")
+			err := responses.SendBadRequest(writer, "changed_since parameter needs to be time in RFC 3339 format")
+			if err != nil {
+				log.Error().Err(err).Msg(responseDataError)
+			}
+			return
+		}
+		clusters = server.clustersChangedSince(clusters, since)
+	}
+
+	sortBy := request.URL.Query().Get(sortParam)
+	if sortBy != "" {
+		order := request.URL.Query().Get(orderParam)
+		if sortBy != sortByHits || (order != "" && order != orderAsc && order != orderDesc) {
+			log.Error().Str("sort", sortBy).Str("order", order).Msg("Improper sorting specification")
+			err := responses.SendBadRequest(writer, "clusters can be sorted by 'hits' in 'asc' or 'desc' order only")
+			if err != nil {
+				log.Error().Err(err).Msg(responseDataError)
+			}
+			return
+		}
+		server.sortClustersByRuleHits(clusters, order == orderAsc)
+	}
+
+	total := len(clusters)
+	truncated := false
+	if maxClusters := server.Config.MaxClustersInOrgList; maxClusters > 0 && total > maxClusters {
+		log.Info().Int("clusters", total).Int("limit", maxClusters).Msg("List of clusters is truncated")
+		clusters = clusters[:maxClusters]
+		truncated = true
+		writer.Header().Set(truncatedHeader, "true")
+		writer.Header().Set(totalCountHeader, strconv.Itoa(total))
+	}
+
+	if acceptsCSV(request) {
+		err = writeClustersAsCSV(writer, organizationID, clusters)
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	response := responses.BuildOkResponseWithData("clusters", clusters)
+	if truncated {
+		response["truncated"] = true
+		response["total"] = total
+	}
+	err = responses.SendOK(writer, response)
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// ruleHitStatsForOrganization returns number of clusters hitting each rule
+// for all clusters that belong to given organization
+func (server *HTTPServer) ruleHitStatsForOrganization(writer http.ResponseWriter, request *http.Request) {
+	organizationID, err := readOrganizationID(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	stats, err := server.Storage.RuleHitStatsForOrg(organizationID)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to compute rule hit statistics")
+		err := responses.SendForbidden(writer, err.Error())
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	err = responses.SendOK(writer, responses.BuildOkResponseWithData("stats", stats))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// clustersChangedSince returns clusters whose reports have been changed after
+// given time
+func (server *HTTPServer) clustersChangedSince(clusters []types.ClusterName, since time.Time) []types.ClusterName {
+	changed := make([]types.ClusterName, 0, len(clusters))
+	for _, cluster := range clusters {
+		if server.Storage.ReportChangedSince(cluster, since) {
+			changed = append(changed, cluster)
+		}
+	}
+	return changed
+}
+
+// sortClustersByRuleHits sorts clusters by number of rule hits in their
+// reports. Clusters with the same number of hits keep their original order.
+func (server *HTTPServer) sortClustersByRuleHits(clusters []types.ClusterName, ascending bool) {
+	reports := make(map[types.ClusterName]types.ClusterReport, len(clusters))
+	for _, cluster := range clusters {
+		// clusters without report are treated as clusters with zero hits
+		report, err := server.Storage.ReadReportForCluster(cluster)
+		if err == nil {
+			reports[cluster] = report
+		}
+	}
+	hits := storage.CountRuleHits(reports)
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if ascending {
+			return hits[clusters[i]] < hits[clusters[j]]
+		}
+		return hits[clusters[i]] > hits[clusters[j]]
+	})
+}
+
+// writeClustersAsCSV writes list of clusters in CSV format, one cluster per row
+func writeClustersAsCSV(writer http.ResponseWriter, organizationID types.OrgID, clusters []types.ClusterName) error {
+	rows := make([][]string, len(clusters))
+	for i, cluster := range clusters {
+		rows[i] = []string{string(cluster)}
+	}
+
+	filename := fmt.Sprintf("clusters_%d.csv", organizationID)
+	return writeCSV(writer, filename, []string{"cluster"}, rows)
+}
+
+func (server *HTTPServer) readReportForCluster(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := server.readClusterNameOrDisplayName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	report, found := server.readReportOrRespond(writer, clusterName)
+	if !found {
+		// everything has been handled already
+		return
+	}
+
+	report, ok := server.transformReport(writer, request.URL.Query(), clusterName, report)
+	if !ok {
+		// everything has been handled already
+		return
+	}
+
+	err = server.writeReport(writer, request, clusterName, report)
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// readReportOrRespond reads report for given cluster. When the report should
+// not be returned (simulated failures, deleted or slow clusters, missing
+// permissions or no rule hits when 204 No Content is configured) the response
+// is written to the writer and false is returned.
+func (server *HTTPServer) readReportOrRespond(writer http.ResponseWriter, clusterName types.ClusterName) (types.ClusterReport, bool) {
+	// when default organization is configured, the report is read in the
+	// same way as by report/{organization}/{cluster} endpoint
+	defaultOrgID := server.Config.DefaultOrgID
+	if defaultOrgID != 0 && !server.checkOrganizationPermissions(writer, defaultOrgID) {
+		return "", false
+	}
+
+	if handleFailureCluster(writer, clusterName) ||
+		server.handleDeletedCluster(writer, clusterName) ||
+		server.handleOrgFailure(writer, server.reportOwner(clusterName), clusterName) ||
+		server.handleSlowCluster(writer, clusterName) {
+		return "", false
+	}
+
+	var report types.ClusterReport
+	var err error
+	if defaultOrgID != 0 {
+		report, err = server.Storage.ReadReportForOrganizationAndCluster(defaultOrgID, clusterName)
+		if err != nil {
+			log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+			err := responses.SendForbidden(writer, err.Error())
+			if err != nil {
+				log.Error().Err(err).Msg("Unable send forbidden response")
+			}
+			return "", false
+		}
+	} else {
+		report, err = server.Storage.ReadReportForCluster(clusterName)
+		if err != nil {
+			log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+			writeError(writer, http.StatusInternalServerError, err.Error())
+			return "", false
+		}
+	}
+
+	if server.Config.NoContentForEmptyReports && report != "" {
+		hits, err := storage.CountReportRuleHits(report)
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to count rule hits in report")
+			writeError(writer, http.StatusInternalServerError, err.Error())
+			return "", false
+		}
+		if hits == 0 {
+			writer.WriteHeader(http.StatusNoContent)
+			return "", false
+		}
+	}
+
+	return report, true
+}
+
+// writeReport writes report into response, converted into legacy v1 "flat"
+// schema when requested by client. Precompressed report is written as is
+// when available and when client accepts gzip encoding.
+func (server *HTTPServer) writeReport(writer http.ResponseWriter, request *http.Request, clusterName types.ClusterName, report types.ClusterReport) error {
+	if report != "" && acceptsGzip(request) && !acceptsYAML(request) && !acceptsLegacyV1(request) {
+		if compressed, found := server.Storage.PrecompressedReport(clusterName, report); found {
+			return writeCompressedJSON(writer, request, compressed)
+		}
+	}
+
+	if report != "" && acceptsLegacyV1(request) {
+		converted, err := storage.ConvertReportToV1(report)
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to convert report into v1 schema")
+			writeError(writer, http.StatusInternalServerError, err.Error())
+			return nil
+		}
+		report = converted
+	}
+
+	return writeJSONOrYAML(writer, request, []byte(report))
+}
+
+// readReportSummaryForCluster returns summary of report for given cluster
+func (server *HTTPServer) readReportSummaryForCluster(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	if handleFailureCluster(writer, clusterName) {
+		return
+	}
+
+	if server.handleSlowCluster(writer, clusterName) {
+		return
+	}
+
+	report, err := server.Storage.ReadReportForCluster(clusterName)
+	if err != nil {
+		log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if report == "" {
+		err := responses.SendNotFound(writer, "report for cluster "+string(clusterName)+" not found")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	summary := storage.SummarizeReport(report)
+	respondJSON(writer, http.StatusOK, responses.BuildOkResponseWithData("summary", summary))
+}
+
+// readReportHistory returns historical snapshots of report for given
+// cluster, the most recent first
+func (server *HTTPServer) readReportHistory(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	limit, err := readLimit(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	response := responses.BuildOkResponseWithData("history", server.Storage.ReportHistory(clusterName, limit))
+	response["cluster"] = clusterName
+	respondJSON(writer, http.StatusOK, response)
+}
+
+// readReportSnapshot returns snapshot of report for given cluster valid at
+// given time: the snapshot taken exactly at that time or the nearest one
+// taken before
+func (server *HTTPServer) readReportSnapshot(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	timestamp, err := getRouterParam(request, "timestamp")
+	if err != nil {
+		return
+	}
+
+	at, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		log.Error().Err(err).Str("timestamp", timestamp).Msg("Improper snapshot timestamp")
+		err := responses.SendBadRequest(writer, "timestamp needs to be in RFC 3339 format")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	snapshot, found := server.Storage.ReportSnapshotAt(clusterName, at)
+	if !found {
+		err := responses.SendNotFound(writer, "no snapshot of report for cluster "+string(clusterName)+" at or before "+timestamp)
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	response := responses.BuildOkResponseWithData("snapshot", snapshot)
+	response["cluster"] = clusterName
+	respondJSON(writer, http.StatusOK, response)
+}
+
+// readRuleHitsByCategory returns number of rule hits in report for given
+// cluster for each rule category (group), categories without hits are
+// included with zero count
+func (server *HTTPServer) readRuleHitsByCategory(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	report, err := server.Storage.ReadReportForCluster(clusterName)
+	if err != nil {
+		log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if report == "" {
+		err := responses.SendNotFound(writer, "report for cluster "+string(clusterName)+" not found")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	content, err := server.Storage.ListOfRulesWithContent()
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to read content of rules")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	rules, err := storage.RuleHitsContent(report, content)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to get content of rules hit by cluster")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// map keys are sorted by JSON encoder, so the output is stable
+	categories := make(map[string]int, len(server.Groups))
+	for _, group := range server.Groups {
+		categories[group.Name] = countRulesInGroup(group, rules)
+	}
+
+	response := responses.BuildOkResponseWithData("categories", categories)
+	response["cluster"] = clusterName
+	respondJSON(writer, http.StatusOK, response)
+}
+
+// readRulesHitByCluster returns identifiers of all rules hit by given
+// cluster, without the rule hits themselves
+func (server *HTTPServer) readRulesHitByCluster(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	report, err := server.Storage.ReadReportForCluster(clusterName)
+	if err != nil {
+		log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if report == "" {
+		err := responses.SendNotFound(writer, "report for cluster "+string(clusterName)+" not found")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	rules, err := storage.RuleHitSelectors(report)
 	if err != nil {
-		return "", err
+		log.Error().Err(err).Msg("Unable to read rule hits from report")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
 	}
-	return types.ClusterName(clusterName), nil
+
+	respondJSON(writer, http.StatusOK, responses.BuildOkResponseWithData("rules", rules))
 }
 
-// getRouterParam retrieves parameter from URL like `/organization/{org_id}`
-func getRouterParam(request *http.Request, paramName string) (string, error) {
-	value, found := mux.Vars(request)[paramName]
-	if !found {
-		return "", errors.New("Missing param")
+// handleFailureCluster checks whether the cluster name follows the failure
+// convention "ffffffff-ffff-ffff-ffff-000000000xxx". If yes, HTTP code xxx is
+// written to the response and true is returned.
+func handleFailureCluster(writer http.ResponseWriter, clusterName types.ClusterName) bool {
+	s := string(clusterName)
+	if !strings.HasPrefix(s, failureClusterIDPrefix) {
+		return false
 	}
 
-	return value, nil
+	log.Info().Str("Cluster name", s).Msg("Failed clusters")
+	suffix := s[len(s)-3:]
+	code, err := strconv.Atoi(suffix)
+	if err != nil {
+		handleServerError(err)
+		return true
+	}
+	log.Info().Int("Code", int(code)).Msg("Failed clusters")
+	if code >= http.StatusInternalServerError {
+		writeError(writer, code, http.StatusText(code))
+		return true
+	}
+	writer.WriteHeader(code)
+	return true
 }
 
-// getRouterPositiveIntParam retrieves parameter from URL like `/organization/{org_id}`
-// and check it for being valid and positive integer, otherwise returns error
-func getRouterPositiveIntParam(request *http.Request, paramName string) (uint64, error) {
-	value, err := getRouterParam(request, paramName)
+// handleDeletedCluster responds with 404 Not Found when the cluster is
+// treated as deleted, even when its report file exists, or when its report
+// has been deleted
+func (server *HTTPServer) handleDeletedCluster(writer http.ResponseWriter, clusterName types.ClusterName) bool {
+	if !server.Storage.IsDeletedCluster(clusterName) && !storage.IsReportDeleted(clusterName) {
+		return false
+	}
+
+	log.Info().Str("Cluster name", string(clusterName)).Msg("Deleted cluster")
+	err := responses.SendNotFound(writer, "report for cluster "+string(clusterName)+" not found")
 	if err != nil {
-		return 0, err
+		log.Error().Err(err).Msg(responseDataError)
 	}
+	return true
+}
 
-	uintValue, err := strconv.ParseUint(value, 10, 64)
+// changingClusterVariant returns information about report variant currently
+// served for "changing cluster" (debug only)
+func (server *HTTPServer) changingClusterVariant(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
 	if err != nil {
-		return 0, err
+		// everything has been handled already
+		return
 	}
 
-	if uintValue == 0 {
-		return 0, err
+	variant, found := server.Storage.GetChangingClusterVariant(clusterName)
+	if !found {
+		err := responses.SendNotFound(writer, "cluster is not changing cluster")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
 	}
 
-	return uintValue, nil
+	err = responses.SendOK(writer, responses.BuildOkResponseWithData("changing_cluster", variant))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
 }
 
-// mainEndpoint will handle the requests for / endpoint
-func (server *HTTPServer) mainEndpoint(writer http.ResponseWriter, _ *http.Request) {
-	err := responses.SendOK(writer, responses.BuildOkResponse())
+// dumpStorage returns summary of data currently held in storage
+func (server *HTTPServer) dumpStorage(writer http.ResponseWriter, request *http.Request) {
+	stats := server.Storage.Stats()
+
+	err := responses.SendOK(writer, responses.BuildOkResponseWithData("storage", stats))
 	if err != nil {
 		log.Error().Err(err).Msg(responseDataError)
 	}
 }
 
-// serveAPISpecFile serves an OpenAPI specifications file specified in config file
-func (server *HTTPServer) serveAPISpecFile(writer http.ResponseWriter, request *http.Request) {
-	absPath, err := filepath.Abs(server.Config.APISpecFile)
+// listLoadedFiles returns list of report files loaded into storage
+func (server *HTTPServer) listLoadedFiles(writer http.ResponseWriter, request *http.Request) {
+	files := server.Storage.LoadedFiles()
+
+	err := responses.SendOK(writer, responses.BuildOkResponseWithData("files", files))
 	if err != nil {
-		const message = "Error creating absolute path of OpenAPI spec file"
-		log.Error().Err(err).Msg(message)
-		handleServerError(err)
-		return
+		log.Error().Err(err).Msg(responseDataError)
 	}
+}
 
-	http.ServeFile(writer, request, absPath)
+// reloadStorage re-reads all data files and returns summary of reloaded data
+func (server *HTTPServer) reloadStorage(writer http.ResponseWriter, request *http.Request) {
+	result := server.Storage.Reload()
+
+	err := responses.SendOK(writer, responses.BuildOkResponseWithData("reload", result))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
 }
 
-// listOfGroups returns the list of defined groups
-func (server *HTTPServer) listOfGroups(writer http.ResponseWriter, request *http.Request) {
-	absPath, err := filepath.Abs(server.Config.APISpecFile)
+// deleteReport deletes report stored for given cluster, until the state is
+// reset or data are reloaded (debug only)
+func (server *HTTPServer) deleteReport(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
 	if err != nil {
-		log.Error().Err(err)
-		handleServerError(err)
+		// everything has been handled already
 		return
 	}
 
-	http.ServeFile(writer, request, absPath)
-}
-
-func (server *HTTPServer) listOfOrganizations(writer http.ResponseWriter, _ *http.Request) {
-	organizations, err := server.Storage.ListOfOrgs()
+	err = server.Storage.DeleteReportForCluster(clusterName)
+	if err == storage.ErrReportNotFound {
+		err := responses.SendNotFound(writer, err.Error())
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("Unable to get list of organizations")
+		log.Error().Err(err).Msg("Unable to delete report")
+		writeError(writer, http.StatusInternalServerError, err.Error())
 		return
 	}
-	err = responses.SendOK(writer, responses.BuildOkResponseWithData("organizations", organizations))
+
+	log.Info().Str("cluster", string(clusterName)).Msg("Report has been deleted")
+	err = responses.SendOK(writer, responses.BuildOkResponse())
 	if err != nil {
 		log.Error().Err(err).Msg(responseDataError)
 	}
 }
 
-func (server *HTTPServer) listOfClustersForOrganization(writer http.ResponseWriter, request *http.Request) {
-	organizationID, err := readOrganizationID(writer, request)
-
+// patchReport applies JSON Merge Patch (RFC 7386) from request body to report
+// stored for given cluster (debug only)
+func (server *HTTPServer) patchReport(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
 	if err != nil {
 		// everything has been handled already
 		return
 	}
 
-	clusters, err := server.Storage.ListOfClustersForOrg(organizationID)
+	if !strings.HasPrefix(request.Header.Get(contentTypeHeader), ContentTypeMergePatch) {
+		err := responses.Send(http.StatusUnsupportedMediaType, writer,
+			responses.BuildResponse("Content-Type needs to be "+ContentTypeMergePatch))
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	var patch interface{}
+	err = server.decodeJSONBody(writer, request, &patch)
 	if err != nil {
-		log.Error().Err(err).Msg("Unable to get list of clusters")
-		handleServerError(err)
-		err := responses.SendForbidden(writer, err.Error())
+		// everything has been handled already
+		return
+	}
+
+	report, err := server.Storage.MergePatchReport(clusterName, patch)
+	if err == storage.ErrReportNotFound {
+		err := responses.SendNotFound(writer, err.Error())
 		if err != nil {
-			log.Error().Err(err).Msg("Unable send forbidden response")
+			log.Error().Err(err).Msg(responseDataError)
 		}
 		return
 	}
-	err = responses.SendOK(writer, responses.BuildOkResponseWithData("clusters", clusters))
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to patch report")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Info().Str("Cluster name", string(clusterName)).Msg("Report has been patched")
+	err = writeJSONOrYAML(writer, request, []byte(report))
 	if err != nil {
 		log.Error().Err(err).Msg(responseDataError)
 	}
 }
 
-func (server *HTTPServer) readReportForCluster(writer http.ResponseWriter, request *http.Request) {
-	clusterName, err := readClusterName(writer, request)
+// readClusterNameFromQuery retrieves cluster name from given query
+// parameter. If it's not possible, it writes http error to the writer and
+// returns error.
+func readClusterNameFromQuery(writer http.ResponseWriter, request *http.Request, param string) (types.ClusterName, error) {
+	clusterName, err := storage.ValidateClusterName(request.URL.Query().Get(param))
+	if err != nil {
+		log.Error().Err(err).Str("param", param).Msg("Improper cluster name")
+		err := responses.SendBadRequest(writer, param+" parameter: "+err.Error())
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return "", err
+	}
+	return clusterName, nil
+}
+
+// diffReports returns rule hits present in report of one cluster only, the
+// clusters are specified by `a` and `b` query parameters (debug only)
+func (server *HTTPServer) diffReports(writer http.ResponseWriter, request *http.Request) {
+	first, err := readClusterNameFromQuery(writer, request, "a")
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+	second, err := readClusterNameFromQuery(writer, request, "b")
 	if err != nil {
 		// everything has been handled already
 		return
 	}
 
-	if strings.HasPrefix(string(clusterName), failureClusterIDPrefix) {
-		s := string(clusterName)
-		log.Info().Str("Cluster name", s).Msg("Failed clusters")
-		suffix := s[len(s)-3:]
-		code, err := strconv.Atoi(suffix)
+	if first == second {
+		err := responses.SendBadRequest(writer, "reports of two different clusters need to be specified")
 		if err != nil {
-			handleServerError(err)
-			return
+			log.Error().Err(err).Msg(responseDataError)
 		}
-		log.Info().Int("Code", int(code)).Msg("Failed clusters")
-		writer.WriteHeader(code)
 		return
 	}
-	report, err := server.Storage.ReadReportForCluster(clusterName)
-	if err != nil {
-		log.Error().Err(err).Msg(unableToReadReportErrorMessage)
-		handleServerError(err)
-		return
+
+	reports := make([]types.ClusterReport, 0, 2)
+	for _, clusterName := range []types.ClusterName{first, second} {
+		report, err := server.Storage.ReadReportForCluster(clusterName)
+		if err != nil {
+			log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+			writeError(writer, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if report == "" {
+			err := responses.SendNotFound(writer, "report for cluster "+string(clusterName)+" not found")
+			if err != nil {
+				log.Error().Err(err).Msg(responseDataError)
+			}
+			return
+		}
+		reports = append(reports, report)
 	}
 
-	r := []byte(report)
-	_, err = writer.Write(r)
+	diff, err := storage.DiffReports(reports[0], reports[1])
 	if err != nil {
-		log.Error().Err(err).Msg(responseDataError)
+		log.Error().Err(err).Msg("Unable to compare reports")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
 	}
+
+	respondJSON(writer, http.StatusOK, responses.BuildOkResponseWithData("diff", diff))
 }
 
 // ClusterList is a data structure that store list of cluster IDs (names).
@@ -214,6 +1252,28 @@ type ClusterList struct {
 	Clusters []string `json:"clusters"`
 }
 
+// Reasons why report for cluster is listed in errors of response with
+// reports for several clusters
+const (
+	ReportErrorNotFound  = "not_found"
+	ReportErrorForbidden = "forbidden"
+	ReportErrorMalformed = "malformed"
+	ReportErrorInternal  = "internal_error"
+)
+
+// errors returned when report for one of several clusters can't be read
+var (
+	errReportNotFound  = errors.New("report not found")
+	errReportForbidden = errors.New("report belongs to organization that can't be accessed")
+	errReportMalformed = errors.New("report can't be parsed")
+)
+
+// ClusterError describes why report for given cluster can't be returned
+type ClusterError struct {
+	Cluster types.ClusterName `json:"cluster"`
+	Reason  string            `json:"reason"`
+}
+
 // ClusterReports is a data structure containing list of clusters, list of
 // errors and dictionary with results per cluster.
 type ClusterReports struct {
@@ -221,6 +1281,14 @@ type ClusterReports struct {
 	Errors      []types.ClusterName               `json:"errors"`
 	Reports     map[types.ClusterName]interface{} `json:"reports"`
 	GeneratedAt string                            `json:"generated_at"`
+	// ErrorDetails contains the same clusters as Errors together with
+	// reasons of the failures, it is provided when enabled by configuration
+	ErrorDetails []ClusterError `json:"error_details,omitempty"`
+	// Truncated is set when not all clusters are returned because of the
+	// limit for number of clusters per request
+	Truncated bool `json:"truncated,omitempty"`
+	// Processing contains clusters whose reports are not ready yet
+	Processing []types.ClusterName `json:"processing,omitempty"`
 }
 
 func (server *HTTPServer) readReportForAllClustersInOrg(writer http.ResponseWriter, request *http.Request) {
@@ -233,66 +1301,272 @@ func (server *HTTPServer) readReportForAllClustersInOrg(writer http.ResponseWrit
 	log.Info().Int("OrgID", int(organizationID)).Msg("Organization ID to get list of results")
 
 	var generatedReports ClusterReports
-	generatedReports.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	generatedReports.GeneratedAt = server.TimestampClock.Now().UTC().Format(time.RFC3339)
 
 	generatedReports.Reports = make(map[types.ClusterName]interface{})
 
-	bytes, err := json.MarshalIndent(generatedReports, "", "\t")
-	if err != nil {
-		log.Error().Err(err).Msg(responseDataError)
-		return
+	server.writeClusterReports(writer, generatedReports)
+}
+
+// clusterListBody is request body with list of clusters before validation,
+// the entries are checked one by one so all problems can be reported
+type clusterListBody struct {
+	Clusters []interface{} `json:"clusters"`
+}
+
+// ValidationError describes one problem found in request body, index
+// of improper item in list is specified when relevant
+type ValidationError struct {
+	Index   *int   `json:"index,omitempty"`
+	Message string `json:"message"`
+}
+
+// validateClusterList checks that list of clusters is specified and that
+// each entry is cluster name (UUID). Cluster names in canonical form are
+// returned together with all problems found.
+func validateClusterList(body clusterListBody) ([]types.ClusterName, []ValidationError) {
+	if body.Clusters == nil {
+		return nil, []ValidationError{{Message: "clusters field is required"}}
 	}
-	_, err = writer.Write(bytes)
-	if err != nil {
-		log.Error().Err(err).Msg(responseDataError)
+
+	clusters := make([]types.ClusterName, 0, len(body.Clusters))
+	validationErrors := []ValidationError{}
+
+	for i, item := range body.Clusters {
+		index := i
+		clusterName, ok := item.(string)
+		if !ok {
+			validationErrors = append(validationErrors, ValidationError{
+				Index:   &index,
+				Message: fmt.Sprintf("cluster name needs to be a string, got %v", item),
+			})
+			continue
+		}
+
+		validated, err := storage.ValidateClusterName(clusterName)
+		if err != nil {
+			validationErrors = append(validationErrors, ValidationError{
+				Index:   &index,
+				Message: err.Error(),
+			})
+			continue
+		}
+		clusters = append(clusters, validated)
 	}
+
+	return clusters, validationErrors
 }
 
 func (server *HTTPServer) readReportForClusters(writer http.ResponseWriter, request *http.Request) {
-	var clusterList ClusterList
-	var generatedReports ClusterReports
-	generatedReports.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	var body clusterListBody
 
-	generatedReports.Reports = make(map[types.ClusterName]interface{})
+	err := server.decodeJSONBody(writer, request, &body)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	clusters, validationErrors := validateClusterList(body)
+	if len(validationErrors) > 0 {
+		log.Error().Int("errors", len(validationErrors)).Msg("Improper list of clusters")
+		response := responses.BuildResponse(invalidRequestBodyMessage)
+		response["errors"] = validationErrors
+		err := responses.Send(http.StatusBadRequest, writer, response)
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	if acceptsNDJSON(request) {
+		server.streamClusterReports(writer, clusters)
+		return
+	}
 
-	err := json.NewDecoder(request.Body).Decode(&clusterList)
+	generatedReports := server.collectReports(clusters)
+	server.separateProcessingClusters(&generatedReports)
+	server.writeClusterReports(writer, generatedReports)
+}
 
-	if err != nil {
-		log.Error().Err(err).Msg("getting list of clusters")
-		http.Error(writer, err.Error(), http.StatusBadRequest)
+// readReportForClustersByPattern returns reports for all clusters whose name
+// matches glob pattern specified in query parameter
+func (server *HTTPServer) readReportForClustersByPattern(writer http.ResponseWriter, request *http.Request) {
+	glob := request.URL.Query().Get(globParam)
+	if glob == "" {
+		err := responses.SendBadRequest(writer, "glob parameter needs to be specified")
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
 		return
 	}
 
-	for _, clusterName := range clusterList.Clusters {
-		log.Info().Str("cluster name", clusterName).Msg("result for cluster")
-		clusterName := types.ClusterName(clusterName)
-		reportStr, err := server.Storage.ReadReportForCluster(clusterName)
+	clusters, err := server.Storage.ClustersMatchingPattern(glob)
+	if err != nil {
+		log.Error().Err(err).Str("glob", glob).Msg("Improper cluster name pattern")
+		err := responses.SendBadRequest(writer, err.Error())
 		if err != nil {
-			log.Error().Err(err).Msg(unableToReadReportErrorMessage)
-			generatedReports.Errors = append(generatedReports.Errors, clusterName)
-			// if error happen, simply go to the next cluster
-			continue
+			log.Error().Err(err).Msg(responseDataError)
 		}
-		var report interface{}
-		err = json.Unmarshal([]byte(reportStr), &report)
+		return
+	}
+
+	truncated := false
+	if maxClusters := server.maxClustersPerRequest(); len(clusters) > maxClusters {
+		log.Info().Int("matches", len(clusters)).Int("limit", maxClusters).Msg("List of clusters is truncated")
+		clusters = clusters[:maxClusters]
+		truncated = true
+	}
+
+	generatedReports := server.collectReports(clusters)
+	generatedReports.Truncated = truncated
+	server.writeClusterReports(writer, generatedReports)
+}
+
+// collectReports reads reports for all given clusters. Clusters for which
+// the report can't be read are listed as errors.
+func (server *HTTPServer) collectReports(clusters []types.ClusterName) ClusterReports {
+	var generatedReports ClusterReports
+	generatedReports.GeneratedAt = server.TimestampClock.Now().UTC().Format(time.RFC3339)
+
+	generatedReports.Reports = make(map[types.ClusterName]interface{})
+
+	for _, clusterName := range clusters {
+		report, err := server.readParsedReport(clusterName)
 		if err != nil {
-			log.Error().Err(err).Msg("Unable to unmarshal report for cluster")
 			generatedReports.Errors = append(generatedReports.Errors, clusterName)
+			if server.Config.ReportErrorDetails {
+				generatedReports.ErrorDetails = append(generatedReports.ErrorDetails, ClusterError{
+					Cluster: clusterName,
+					Reason:  reportErrorReason(err),
+				})
+			}
 			// if error happen, simply go to the next cluster
 			continue
 		}
 		generatedReports.ClusterList = append(generatedReports.ClusterList, clusterName)
 		generatedReports.Reports[clusterName] = report
 	}
-	bytes, err := json.MarshalIndent(generatedReports, "", "\t")
+
+	return generatedReports
+}
+
+// reportErrorReason returns reason of failure reported for cluster whose
+// report can't be read by readParsedReport
+func reportErrorReason(err error) string {
+	switch {
+	case errors.Is(err, errReportNotFound):
+		return ReportErrorNotFound
+	case errors.Is(err, errReportForbidden):
+		return ReportErrorForbidden
+	case errors.Is(err, errReportMalformed):
+		return ReportErrorMalformed
+	default:
+		return ReportErrorInternal
+	}
+}
+
+// isReportForbidden checks whether report for given cluster is read for
+// organization that can't be accessed. The default organization is checked
+// when configured, all organizations owning the cluster otherwise.
+func (server *HTTPServer) isReportForbidden(clusterName types.ClusterName) bool {
+	owners := []types.OrgID{server.Config.DefaultOrgID}
+	if server.Config.DefaultOrgID == 0 {
+		owners = server.Storage.OwnersOfCluster(clusterName)
+	}
+
+	for _, orgID := range owners {
+		if _, err := server.Storage.ListOfClustersForOrg(orgID); err != nil {
+			log.Error().Err(err).Uint32("org", uint32(orgID)).Msg(unableToReadReportErrorMessage)
+			return true
+		}
+	}
+	return false
+}
+
+// readParsedReport reads report for given cluster and unmarshals it. Report
+// of cluster owned by organization that can't be accessed is not read.
+func (server *HTTPServer) readParsedReport(clusterName types.ClusterName) (interface{}, error) {
+	log.Info().Str("cluster name", string(clusterName)).Msg("result for cluster")
+	if server.isReportForbidden(clusterName) {
+		return nil, errReportForbidden
+	}
+
+	reportStr, err := server.Storage.ReadReportForCluster(clusterName)
 	if err != nil {
-		log.Error().Err(err).Msg(responseDataError)
-		return
+		log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+		return nil, err
+	}
+	if reportStr == "" {
+		log.Error().Str("cluster name", string(clusterName)).Msg(unableToReadReportErrorMessage)
+		return nil, errReportNotFound
 	}
-	_, err = writer.Write(bytes)
+
+	var report interface{}
+	err = json.Unmarshal([]byte(reportStr), &report)
 	if err != nil {
-		log.Error().Err(err).Msg(responseDataError)
+		log.Error().Err(err).Msg("Unable to unmarshal report for cluster")
+		return nil, fmt.Errorf("%w: %v", errReportMalformed, err)
+	}
+
+	return report, nil
+}
+
+// ClusterReportLine is one line of newline delimited JSON stream with
+// reports for several clusters
+type ClusterReportLine struct {
+	Cluster types.ClusterName `json:"cluster"`
+	Report  interface{}       `json:"report,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// streamClusterReports writes reports for given clusters as newline
+// delimited JSON, one cluster per line. Each line is flushed as soon as it is
+// written, so clients can process the reports progressively.
+func (server *HTTPServer) streamClusterReports(writer http.ResponseWriter, clusters []types.ClusterName) {
+	writer.Header().Set(contentTypeHeader, ContentTypeNDJSON)
+	flusher, _ := writer.(http.Flusher)
+	encoder := json.NewEncoder(writer)
+
+	for _, clusterName := range clusters {
+		line := ClusterReportLine{Cluster: clusterName}
+
+		report, err := server.readParsedReport(clusterName)
+		if err != nil {
+			line.Error = err.Error()
+		} else {
+			line.Report = report
+		}
+
+		// Encode writes newline after each value
+		err = encoder.Encode(line)
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// writeClusterReports writes reports for several clusters into response
+func (server *HTTPServer) writeClusterReports(writer http.ResponseWriter, generatedReports ClusterReports) {
+	respondJSON(writer, http.StatusOK, generatedReports)
+}
+
+// checkOrganizationPermissions checks if the organization can be accessed.
+// If not, 403 Forbidden is written to the writer and false is returned.
+func (server *HTTPServer) checkOrganizationPermissions(writer http.ResponseWriter, organizationID types.OrgID) bool {
+	_, err := server.Storage.ListOfClustersForOrg(organizationID)
+	if err != nil {
+		log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+		err := responses.SendForbidden(writer, err.Error())
+		if err != nil {
+			log.Error().Err(err).Msg("Unable send forbidden response")
+		}
+		return false
 	}
+	return true
 }
 
 func (server *HTTPServer) readReportForOrganizationAndCluster(writer http.ResponseWriter, request *http.Request) {
@@ -308,15 +1582,40 @@ func (server *HTTPServer) readReportForOrganizationAndCluster(writer http.Respon
 		return
 	}
 
+	// checks are performed in following order:
+	// 1. organization permissions (403 for organizations without access)
+	// 2. failure clusters convention (HTTP code taken from cluster ID)
+	// 3. deleted clusters (404)
+	// 4. failures injected for the organization (500)
+	// 5. report lookup itself
+	if !server.checkOrganizationPermissions(writer, organizationID) {
+		return
+	}
+
+	if handleFailureCluster(writer, clusterName) {
+		return
+	}
+
+	if server.handleDeletedCluster(writer, clusterName) {
+		return
+	}
+
+	if server.handleOrgFailure(writer, organizationID, clusterName) {
+		return
+	}
+
 	report, err := server.Storage.ReadReportForOrganizationAndCluster(organizationID, clusterName)
 	if err != nil {
+		// cluster is not owned by the organization
 		log.Error().Err(err).Msg(unableToReadReportErrorMessage)
-		handleServerError(err)
+		err := responses.SendForbidden(writer, err.Error())
+		if err != nil {
+			log.Error().Err(err).Msg("Unable send forbidden response")
+		}
 		return
 	}
 
-	r := []byte(report)
-	_, err = writer.Write(r)
+	err = server.writeReport(writer, request, clusterName, report)
 	if err != nil {
 		log.Error().Err(err).Msg(responseDataError)
 	}
@@ -345,12 +1644,12 @@ func parseRuleSelector(ruleSelector types.RuleSelector) (types.Component, types.
 	return types.Component(splitedRuleID[0]), types.ErrorKey(splitedRuleID[1]), nil
 }
 
-func readClustersHittingRule(component types.Component, errorKey types.ErrorKey) []types.ClusterName {
+func (server *HTTPServer) readClustersHittingRule(component types.Component, errorKey types.ErrorKey) []types.ClusterName {
 	var clusterList []types.ClusterName
 
 	// TODO: quick and dirty linear search should be imroved later if required
 	for _, ruleHit := range data.RuleHits {
-		if ruleHit.Component == component && ruleHit.ErrorKey == errorKey {
+		if ruleHit.Component == component && ruleHit.ErrorKey == errorKey && !server.Storage.IsDeletedCluster(ruleHit.Cluster) {
 			clusterList = append(clusterList, ruleHit.Cluster)
 		}
 	}
@@ -396,14 +1695,14 @@ func (server *HTTPServer) ruleClusterDetailEndpoint(writer http.ResponseWriter,
 		Str("component", string(component)).
 		Str("error key", string(errorKey)).
 		Msg("Reading clusters hitting given rule")
-	clusters := readClustersHittingRule(component, errorKey)
+	clusters := server.readClustersHittingRule(component, errorKey)
 	log.Info().Int("cluster count", len(clusters)).Msg("Clusters hitting the rule")
 
 	// prepare response
 	var hittingClusters HittingClusters
 
 	// first fill-in metadata
-	hittingClusters.Metadata.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	hittingClusters.Metadata.GeneratedAt = server.TimestampClock.Now().UTC().Format(time.RFC3339)
 	hittingClusters.Metadata.Count = len(clusters)
 	hittingClusters.Metadata.Component = component
 	hittingClusters.Metadata.ErrorKey = errorKey
@@ -411,13 +1710,5 @@ func (server *HTTPServer) ruleClusterDetailEndpoint(writer http.ResponseWriter,
 	// second fill-in list of clusters
 	hittingClusters.ClusterList = clusters
 
-	bytes, err := json.MarshalIndent(hittingClusters, "", "\t")
-	if err != nil {
-		log.Error().Err(err).Msg(responseDataError)
-		return
-	}
-	_, err = writer.Write(bytes)
-	if err != nil {
-		log.Error().Err(err).Msg(responseDataError)
-	}
+	respondJSON(writer, http.StatusOK, hittingClusters)
 }
@@ -0,0 +1,143 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/rs/zerolog/log"
+)
+
+// Build information returned by info endpoint. These variables are filled
+// by the service from its own build variables that are set via -ldflags
+// (see Makefile).
+var (
+	// BuildVersion contains the major.minor version of the service
+	BuildVersion = "*not set*"
+
+	// BuildTime contains timestamp when the service has been built
+	BuildTime = "*not set*"
+
+	// BuildBranch contains Git branch used to build the service
+	BuildBranch = "*not set*"
+
+	// BuildCommit contains Git commit used to build the service
+	BuildCommit = "*not set*"
+)
+
+// ServiceInfo represents build information about running service
+type ServiceInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	Branch    string `json:"branch"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+}
+
+// serviceInfo returns build information about running service
+func (server *HTTPServer) serviceInfo(writer http.ResponseWriter, _ *http.Request) {
+	info := ServiceInfo{
+		Version:   BuildVersion,
+		BuildTime: BuildTime,
+		Branch:    BuildBranch,
+		Commit:    BuildCommit,
+		GoVersion: runtime.Version(),
+	}
+
+	err := responses.SendOK(writer, responses.BuildOkResponseWithData("info", info))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// ReportSchemaVersion identifies schema of reports served by the mock,
+// it is changed whenever the schema changes in incompatible way
+const ReportSchemaVersion = "2"
+
+// Response features supported by report endpoints
+const (
+	// FeatureLegacyV1Schema - reports in v1 "flat" schema (profile=v1)
+	FeatureLegacyV1Schema = "v1_schema"
+	// FeatureYAML - reports in YAML format
+	FeatureYAML = "yaml"
+	// FeatureNDJSON - reports for several clusters as newline delimited JSON
+	FeatureNDJSON = "ndjson"
+	// FeatureGzip - precompressed reports
+	FeatureGzip = "gzip"
+	// FeatureEmptyReport - reports without rule hits (empty parameter)
+	FeatureEmptyReport = "empty"
+	// FeatureInflatedReport - reports with synthetic rule hits (inflate
+	// parameter)
+	FeatureInflatedReport = "inflate"
+	// FeatureMinRisk - rule hits filtered by total risk (minRisk parameter)
+	FeatureMinRisk = "min_risk"
+	// FeatureOrderBy - rule hits sorted by order_by parameter
+	FeatureOrderBy = "order_by"
+	// FeatureRuleHitFilters - rule hits filtered by impacting and category
+	// parameters
+	FeatureRuleHitFilters = "rule_hit_filters"
+	// FeatureSummary - summary of report
+	FeatureSummary = "summary"
+	// FeatureStream - report streamed by Server-Sent Events
+	FeatureStream = "stream"
+	// FeatureWebSocket - reports pushed via WebSocket
+	FeatureWebSocket = "websocket"
+	// FeatureMergePatch - reports changed by JSON Merge Patch (debug only)
+	FeatureMergePatch = "merge_patch"
+)
+
+// reportFeatures returns all supported response features
+func reportFeatures() []string {
+	return []string{
+		FeatureLegacyV1Schema,
+		FeatureYAML,
+		FeatureNDJSON,
+		FeatureGzip,
+		FeatureEmptyReport,
+		FeatureInflatedReport,
+		FeatureMinRisk,
+		FeatureOrderBy,
+		FeatureRuleHitFilters,
+		FeatureSummary,
+		FeatureStream,
+		FeatureWebSocket,
+		FeatureMergePatch,
+	}
+}
+
+// ReportSchema describes schema of reports and features supported by report
+// endpoints
+type ReportSchema struct {
+	Version  string   `json:"version"`
+	Features []string `json:"features"`
+}
+
+// reportSchema returns schema version and features supported by the mock, so
+// clients can check they are compatible with it
+func (server *HTTPServer) reportSchema(writer http.ResponseWriter, _ *http.Request) {
+	schema := ReportSchema{
+		Version:  ReportSchemaVersion,
+		Features: reportFeatures(),
+	}
+
+	err := responses.SendOK(writer, responses.BuildOkResponseWithData("schema", schema))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
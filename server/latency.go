@@ -0,0 +1,115 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// supported latency distributions
+const (
+	latencyConstant    = "constant"
+	latencyUniform     = "uniform"
+	latencyNormal      = "normal"
+	latencyExponential = "exponential"
+)
+
+// latencyHeader contains delay added to the response, it is set in debug
+// mode only
+const latencyHeader = "X-Mock-Latency"
+
+// latencySampler returns delay that should be added to one response
+type latencySampler func() time.Duration
+
+// newLatencySampler constructs sampler for latency distribution selected in
+// configuration
+func (server *HTTPServer) newLatencySampler() (latencySampler, error) {
+	config := server.Config
+
+	generator := &randomGenerator{
+		// disable "G404 (CWE-338): Use of weak random number generator"
+		// reproducibility is required there
+		// #nosec G404
+		random: rand.New(rand.NewSource(config.LatencySeed)),
+	}
+
+	switch config.LatencyDistribution {
+	case latencyConstant:
+		return func() time.Duration {
+			return config.LatencyMean
+		}, nil
+	case latencyUniform:
+		if config.LatencyMax <= config.LatencyMin {
+			return nil, fmt.Errorf("latency_max needs to be greater than latency_min")
+		}
+		return func() time.Duration {
+			return config.LatencyMin + generator.duration(config.LatencyMax-config.LatencyMin)
+		}, nil
+	case latencyNormal:
+		return func() time.Duration {
+			delay := float64(config.LatencyMean) + generator.normFloat64()*float64(config.LatencyStdDev)
+			// negative delay is not possible
+			if delay < 0 {
+				return 0
+			}
+			return time.Duration(delay)
+		}, nil
+	case latencyExponential:
+		return func() time.Duration {
+			return time.Duration(generator.expFloat64() * float64(config.LatencyMean))
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown latency distribution '%s'", config.LatencyDistribution)
+	}
+}
+
+// newLatencyMiddleware constructs middleware that delays each response by
+// duration sampled from configured latency distribution
+func (server *HTTPServer) newLatencyMiddleware() (mux.MiddlewareFunc, error) {
+	sampler, err := server.newLatencySampler()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("distribution", server.Config.LatencyDistribution).
+		Int64("seed", server.Config.LatencySeed).
+		Msg("Response latency is enabled")
+
+	return func(nextHandler http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if server.isHealthEndpoint(r) {
+					nextHandler.ServeHTTP(w, r)
+					return
+				}
+
+				delay := sampler()
+				if server.Config.Debug {
+					w.Header().Set(latencyHeader, delay.String())
+				}
+				time.Sleep(delay)
+				nextHandler.ServeHTTP(w, r)
+			})
+	}, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// MultiReportRequestItem selects one cluster report together with
+// organization it is read for
+type MultiReportRequestItem struct {
+	OrgID   types.OrgID       `json:"org_id"`
+	Cluster types.ClusterName `json:"cluster"`
+}
+
+// MultiReportItem is report (or error) for one item of multi report request,
+// HTTP status code is specified for each item separately
+type MultiReportItem struct {
+	OrgID   types.OrgID       `json:"org_id"`
+	Cluster types.ClusterName `json:"cluster"`
+	Status  int               `json:"status"`
+	Report  interface{}       `json:"report,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// MultiReports is response for multi report request
+type MultiReports struct {
+	Reports     []MultiReportItem `json:"reports"`
+	GeneratedAt string            `json:"generated_at"`
+	Status      string            `json:"status"`
+}
+
+// readReportsForOrganizationsAndClusters returns reports for list of
+// organization and cluster pairs. Permissions are checked for each pair
+// separately, so one response can contain both reports and errors.
+func (server *HTTPServer) readReportsForOrganizationsAndClusters(writer http.ResponseWriter, request *http.Request) {
+	var items []MultiReportRequestItem
+
+	err := server.decodeJSONBody(writer, request, &items)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	if maxItems := server.maxClustersPerRequest(); len(items) > maxItems {
+		log.Error().Int("items", len(items)).Int("limit", maxItems).Msg("Too many items in multi report request")
+		message := fmt.Sprintf("at most %d items can be requested at once", maxItems)
+		err := responses.SendBadRequest(writer, message)
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+		return
+	}
+
+	multiReports := MultiReports{
+		Reports:     make([]MultiReportItem, 0, len(items)),
+		GeneratedAt: server.TimestampClock.Now().UTC().Format(time.RFC3339),
+		Status:      "ok",
+	}
+	for _, item := range items {
+		multiReports.Reports = append(multiReports.Reports, server.readMultiReportItem(item))
+	}
+
+	respondJSON(writer, http.StatusOK, multiReports)
+}
+
+// readMultiReportItem reads report for one organization and cluster pair.
+// The checks are performed in the same order as for report for organization
+// and cluster endpoint.
+func (server *HTTPServer) readMultiReportItem(item MultiReportRequestItem) MultiReportItem {
+	result := MultiReportItem{
+		OrgID:   item.OrgID,
+		Cluster: item.Cluster,
+	}
+
+	clusterName, err := storage.ValidateClusterName(string(item.Cluster))
+	if err != nil {
+		result.Status = http.StatusBadRequest
+		result.Error = err.Error()
+		return result
+	}
+	result.Cluster = clusterName
+
+	_, err = server.Storage.ListOfClustersForOrg(item.OrgID)
+	if err != nil {
+		result.Status = http.StatusForbidden
+		result.Error = err.Error()
+		return result
+	}
+
+	if server.orgFailures.shouldFail(item.OrgID, clusterName) {
+		result.Status = http.StatusInternalServerError
+		result.Error = http.StatusText(http.StatusInternalServerError)
+		return result
+	}
+
+	reportStr, err := server.Storage.ReadReportForOrganizationAndCluster(item.OrgID, clusterName)
+	if err != nil {
+		// cluster is not owned by the organization
+		result.Status = http.StatusForbidden
+		result.Error = err.Error()
+		return result
+	}
+
+	var report interface{}
+	err = json.Unmarshal([]byte(reportStr), &report)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to unmarshal report for cluster")
+		result.Status = http.StatusInternalServerError
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = http.StatusOK
+	result.Report = report
+	return result
+}
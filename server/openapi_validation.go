@@ -0,0 +1,100 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// loadAPISpecRouter loads OpenAPI specification from APISpecFile and
+// constructs router that is able to find operation for incoming request.
+// Paths in the specification are relative to API prefix.
+func (server *HTTPServer) loadAPISpecRouter() (routers.Router, error) {
+	loader := openapi3.NewLoader()
+	spec, err := loader.LoadFromFile(server.Config.APISpecFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// examples in the specification are not relevant for request
+	// validation
+	err = spec.Validate(context.Background(), openapi3.DisableExamplesValidation())
+	if err != nil {
+		return nil, err
+	}
+
+	spec.Servers = openapi3.Servers{
+		{URL: strings.TrimSuffix(server.apiPrefix(), "/")},
+	}
+
+	return gorillamux.NewRouter(spec)
+}
+
+// newRequestValidationMiddleware returns middleware that validates incoming
+// requests against OpenAPI specification. Requests that does not conform to
+// the specification are refused with 400 Bad Request. Requests to endpoints
+// that are not described in the specification are passed through.
+func (server *HTTPServer) newRequestValidationMiddleware() (mux.MiddlewareFunc, error) {
+	specRouter, err := server.loadAPISpecRouter()
+	if err != nil {
+		return nil, err
+	}
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return func(nextHandler http.Handler) http.Handler {
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				route, pathParams, err := specRouter.FindRoute(r)
+				if err != nil {
+					// endpoint or method not described in spec
+					nextHandler.ServeHTTP(w, r)
+					return
+				}
+
+				input := &openapi3filter.RequestValidationInput{
+					Request:    r,
+					PathParams: pathParams,
+					Route:      route,
+					Options:    options,
+				}
+				err = openapi3filter.ValidateRequest(r.Context(), input)
+				if err != nil {
+					log.Warn().Err(err).Str("path", r.URL.Path).Msg("Request does not conform to OpenAPI specification")
+					err = responses.SendBadRequest(w, err.Error())
+					if err != nil {
+						log.Error().Err(err).Msg(responseDataError)
+					}
+					return
+				}
+
+				nextHandler.ServeHTTP(w, r)
+			})
+	}, nil
+}
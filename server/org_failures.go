@@ -0,0 +1,115 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// orgFailureInjector decides whether request for report of organization's
+// cluster fails, each organization has its own failure probability
+type orgFailureInjector struct {
+	probabilities map[types.OrgID]float64
+	generator     *randomGenerator
+}
+
+// newOrgFailureInjector constructs failure injector according to
+// OrgFailureProbability and OrgFailureSeed options. Nil is returned when no
+// organization has failure probability set.
+func newOrgFailureInjector(config Configuration) *orgFailureInjector {
+	probabilities := make(map[types.OrgID]float64, len(config.OrgFailureProbability))
+	for key, probability := range config.OrgFailureProbability {
+		orgID, err := strconv.ParseUint(key, 10, 32)
+		if err != nil || probability < 0 || probability > 1 {
+			log.Error().Str("org", key).Float64("probability", probability).Msg("Improper failure probability for organization, it is ignored")
+			continue
+		}
+		if probability > 0 {
+			probabilities[types.OrgID(orgID)] = probability
+		}
+	}
+
+	if len(probabilities) == 0 {
+		return nil
+	}
+
+	log.Info().
+		Int("organizations", len(probabilities)).
+		Int64("seed", config.OrgFailureSeed).
+		Msg("Failures for organizations are enabled")
+
+	return &orgFailureInjector{
+		probabilities: probabilities,
+		generator: &randomGenerator{
+			// disable "G404 (CWE-338): Use of weak random number generator"
+			// reproducibility is required there
+			// #nosec G404
+			random: rand.New(rand.NewSource(config.OrgFailureSeed)),
+		},
+	}
+}
+
+// shouldFail checks whether request for report of given organization's
+// cluster fails. Each injected failure is logged.
+func (injector *orgFailureInjector) shouldFail(orgID types.OrgID, clusterName types.ClusterName) bool {
+	if injector == nil {
+		return false
+	}
+
+	probability, found := injector.probabilities[orgID]
+	if !found || injector.generator.float64() >= probability {
+		return false
+	}
+
+	log.Warn().
+		Uint32("org", uint32(orgID)).
+		Str("cluster", string(clusterName)).
+		Msg("Injecting internal server error for organization")
+	return true
+}
+
+// reportOwner returns organization the report for given cluster is read
+// for: the default organization when configured, the owner of the cluster
+// otherwise
+func (server *HTTPServer) reportOwner(clusterName types.ClusterName) types.OrgID {
+	if server.Config.DefaultOrgID != 0 {
+		return server.Config.DefaultOrgID
+	}
+
+	orgID, err := server.Storage.GetOrgIDByClusterID(clusterName)
+	if err != nil {
+		return 0
+	}
+	return orgID
+}
+
+// handleOrgFailure responds with 500 Internal Server Error when failure is
+// injected for given organization's cluster
+func (server *HTTPServer) handleOrgFailure(writer http.ResponseWriter, orgID types.OrgID, clusterName types.ClusterName) bool {
+	if !server.orgFailures.shouldFail(orgID, clusterName) {
+		return false
+	}
+
+	writeError(writer, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	return true
+}
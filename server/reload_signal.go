@@ -0,0 +1,53 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+)
+
+// reloadOnSignal starts reloading of data files each time the service
+// receives SIGHUP. Returned function stops the handling.
+func (server *HTTPServer) reloadOnSignal() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for received := range signals {
+			log.Info().Str("signal", received.String()).Msg("Reloading data files")
+			result := server.Storage.Reload()
+			for _, failure := range result.Failures {
+				log.Warn().Str("file", failure.File).Str("error", failure.Error).Msg("Data file is not reloaded")
+			}
+			log.Info().
+				Int("reports", result.ReportsCount).
+				Int("report templates", result.ReportTemplatesCount).
+				Int("failures", len(result.Failures)).
+				Msg("Data files reloaded")
+		}
+	}()
+
+	return func() {
+		// no signal is delivered into the channel after Stop returns
+		signal.Stop(signals)
+		close(signals)
+	}
+}
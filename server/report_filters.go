@@ -0,0 +1,111 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+const (
+	// impactingParam is query parameter that selects only rule hits for
+	// active (impacting) rules when set to true
+	impactingParam = "impacting"
+
+	// categoryParam is query parameter with name of rule category (group)
+	// that rule hits need to belong to
+	categoryParam = "category"
+)
+
+// ruleHitFilter constructs predicate for rule hits from query parameter
+// value
+type ruleHitFilter func(server *HTTPServer, value string) (storage.RuleHitPredicate, error)
+
+// ruleHitFilters maps query parameters to constructors of rule hit
+// predicates. Parameters not listed there are ignored.
+var ruleHitFilters = map[string]ruleHitFilter{
+	impactingParam: impactingFilter,
+	categoryParam:  categoryFilter,
+}
+
+// impactingFilter keeps rule hits for active rules only, or for inactive
+// rules only when the value is false
+func impactingFilter(_ *HTTPServer, value string) (storage.RuleHitPredicate, error) {
+	impacting, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s parameter needs to be a boolean", impactingParam)
+	}
+
+	return func(rule types.RuleWithContent) bool {
+		return rule.Active == impacting
+	}, nil
+}
+
+// categoryFilter keeps rule hits tagged by at least one tag belonging to
+// given category. Category is selected by its key or by its name.
+func categoryFilter(server *HTTPServer, value string) (storage.RuleHitPredicate, error) {
+	for key, group := range server.Groups {
+		if strings.EqualFold(key, value) || strings.EqualFold(group.Name, value) {
+			tags := group.Tags
+			return func(rule types.RuleWithContent) bool {
+				return hasAnyTag(rule.Tags, tags)
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown %s %q", categoryParam, value)
+}
+
+// hasAnyTag checks if at least one of wanted tags is in the list of tags
+func hasAnyTag(tags, wanted []string) bool {
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if tag == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ruleHitPredicates returns predicates for all rule hit filters requested by
+// query parameters, in stable order
+func (server *HTTPServer) ruleHitPredicates(query url.Values) ([]storage.RuleHitPredicate, error) {
+	params := make([]string, 0, len(ruleHitFilters))
+	for param := range ruleHitFilters {
+		if query.Get(param) != "" {
+			params = append(params, param)
+		}
+	}
+	sort.Strings(params)
+
+	predicates := make([]storage.RuleHitPredicate, 0, len(params))
+	for _, param := range params {
+		predicate, err := ruleHitFilters[param](server, query.Get(param))
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
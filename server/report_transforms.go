@@ -0,0 +1,204 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// badRequestError is returned by report transformations when query parameter
+// has improper value, the message is sent to client as is
+type badRequestError struct {
+	message string
+}
+
+func (err badRequestError) Error() string {
+	return err.message
+}
+
+// reportTransform modifies report according to query parameters. Parameters
+// not used by the transformation are ignored.
+type reportTransform func(report types.ClusterReport, query url.Values) (types.ClusterReport, error)
+
+// reportTransforms returns all transformations of report for given cluster,
+// in the order in which they are applied
+func (server *HTTPServer) reportTransforms(clusterName types.ClusterName) []reportTransform {
+	return []reportTransform{
+		emptyReportTransform,
+		fractionTransform,
+		inflateTransform,
+		server.minRiskTransform,
+		server.ruleHitFiltersTransform,
+		server.orderByTransform,
+		server.clusterInfoTransform(clusterName),
+	}
+}
+
+// transformReport applies all transformations to report. Error response is
+// written to the writer and false is returned when any of them fails.
+func (server *HTTPServer) transformReport(writer http.ResponseWriter, query url.Values, clusterName types.ClusterName, report types.ClusterReport) (types.ClusterReport, bool) {
+	for _, transform := range server.reportTransforms(clusterName) {
+		var err error
+		report, err = transform(report, query)
+		if err == nil {
+			continue
+		}
+
+		var badRequest badRequestError
+		if errors.As(err, &badRequest) {
+			log.Error().Err(err).Msg("Improper report parameter")
+			err := responses.SendBadRequest(writer, badRequest.message)
+			if err != nil {
+				log.Error().Err(err).Msg(responseDataError)
+			}
+			return report, false
+		}
+
+		log.Error().Err(err).Msg("Unable to transform report")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return report, false
+	}
+	return report, true
+}
+
+// emptyReportTransform strips all rule hits from report when requested by
+// empty parameter
+func emptyReportTransform(report types.ClusterReport, query url.Values) (types.ClusterReport, error) {
+	empty := query.Get(emptyParam)
+	if empty == "" {
+		return report, nil
+	}
+
+	stripHits, err := strconv.ParseBool(empty)
+	if err != nil {
+		return report, badRequestError{"empty parameter needs to be a boolean value"}
+	}
+	if !stripHits {
+		return report, nil
+	}
+	return storage.EmptyReport(report)
+}
+
+// fractionTransform keeps only given fraction of rule hits in report
+func fractionTransform(report types.ClusterReport, query url.Values) (types.ClusterReport, error) {
+	fraction := query.Get(fractionParam)
+	if fraction == "" {
+		return report, nil
+	}
+
+	value, err := strconv.ParseFloat(fraction, 64)
+	if err != nil || value < 0 || value > 1 {
+		return report, badRequestError{"fraction parameter needs to be a number in range 0.0..1.0"}
+	}
+	return storage.SampleReport(report, value)
+}
+
+// inflateTransform adds given number of synthetic rule hits into report
+func inflateTransform(report types.ClusterReport, query url.Values) (types.ClusterReport, error) {
+	inflate := query.Get(inflateParam)
+	if inflate == "" {
+		return report, nil
+	}
+
+	count, err := strconv.Atoi(inflate)
+	if err != nil || count < 0 || count > storage.MaxReportInflation {
+		message := fmt.Sprintf("inflate parameter needs to be an integer in range 0..%d", storage.MaxReportInflation)
+		return report, badRequestError{message}
+	}
+	return storage.InflateReport(report, count)
+}
+
+// minRiskTransform keeps only rule hits with total risk greater than or equal
+// to given threshold
+func (server *HTTPServer) minRiskTransform(report types.ClusterReport, query url.Values) (types.ClusterReport, error) {
+	minRisk := query.Get(minRiskParam)
+	if minRisk == "" {
+		return report, nil
+	}
+
+	risk, err := strconv.Atoi(minRisk)
+	if err != nil {
+		return report, badRequestError{"minRisk parameter needs to be an integer"}
+	}
+
+	rules, err := server.Storage.ListOfRulesWithContent()
+	if err != nil {
+		return report, err
+	}
+	return storage.FilterReportByTotalRisk(report, rules, risk)
+}
+
+// ruleHitFiltersTransform keeps only rule hits satisfying all rule hit
+// filters requested by query parameters
+func (server *HTTPServer) ruleHitFiltersTransform(report types.ClusterReport, query url.Values) (types.ClusterReport, error) {
+	predicates, err := server.ruleHitPredicates(query)
+	if err != nil {
+		return report, badRequestError{err.Error()}
+	}
+	if len(predicates) == 0 {
+		return report, nil
+	}
+
+	rules, err := server.Storage.ListOfRulesWithContent()
+	if err != nil {
+		return report, err
+	}
+	return storage.FilterReportRuleHits(report, rules, predicates...)
+}
+
+// orderByTransform sorts rule hits in report by key given by order_by
+// parameter
+func (server *HTTPServer) orderByTransform(report types.ClusterReport, query url.Values) (types.ClusterReport, error) {
+	orderBy := query.Get(orderByParam)
+	if orderBy == "" {
+		return report, nil
+	}
+
+	rules, err := server.Storage.ListOfRulesWithContent()
+	if err != nil {
+		return report, err
+	}
+
+	report, err = storage.SortReportRuleHits(report, rules, orderBy)
+	if err == storage.ErrUnknownRuleHitsOrder {
+		message := fmt.Sprintf("order_by parameter needs to be one of %s, %s, %s",
+			storage.RuleHitsInFileOrder, storage.RuleHitsByRuleID, storage.RuleHitsByTotalRisk)
+		return report, badRequestError{message}
+	}
+	return report, err
+}
+
+// clusterInfoTransform returns transformation that adds information about
+// given cluster into report when enabled by configuration
+func (server *HTTPServer) clusterInfoTransform(clusterName types.ClusterName) reportTransform {
+	return func(report types.ClusterReport, _ url.Values) (types.ClusterReport, error) {
+		if !server.Config.ClusterInfo || report == "" {
+			return report, nil
+		}
+		return storage.AddClusterInfoToReport(report, server.Storage.ClusterInfo(clusterName))
+	}
+}
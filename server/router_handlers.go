@@ -0,0 +1,87 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+const allowHeader = "Allow"
+
+// methods checked when the list of allowed methods is constructed
+var knownMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// allowedMethods returns all methods registered in router for path from the
+// request
+func allowedMethods(router *mux.Router, request *http.Request) []string {
+	methods := make([]string, 0)
+
+	for _, method := range knownMethods {
+		probe := request.Clone(request.Context())
+		probe.Method = method
+
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods
+}
+
+// newMethodNotAllowedHandler returns handler that responds with 405 Method
+// Not Allowed and lists methods registered for the path in Allow header
+func newMethodNotAllowedHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		methods := allowedMethods(router, request)
+		log.Info().
+			Str("method", request.Method).
+			Str("path", request.URL.Path).
+			Strs("allowed", methods).
+			Msg("Method not allowed")
+
+		writer.Header().Set(allowHeader, strings.Join(methods, ", "))
+		err := responses.Send(http.StatusMethodNotAllowed, writer, responses.BuildResponse(http.StatusText(http.StatusMethodNotAllowed)))
+		if err != nil {
+			log.Error().Err(err).Msg(responseDataError)
+		}
+	})
+}
+
+// notFoundHandler responds with 404 Not Found in JSON format for all paths
+// that are not registered in router
+func notFoundHandler(writer http.ResponseWriter, request *http.Request) {
+	log.Info().Str("path", request.URL.Path).Msg("Endpoint not found")
+
+	err := responses.SendNotFound(writer, http.StatusText(http.StatusNotFound))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
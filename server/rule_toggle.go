@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// mockUserID is used for rule toggles as the mock does not authenticate users
+const mockUserID = types.UserID("1")
+
+// readRuleID retrieves rule ID from request
+func readRuleID(writer http.ResponseWriter, request *http.Request) (types.RuleID, error) {
+	ruleID, err := getRouterParam(request, "rule_id")
+	if err != nil {
+		return "", err
+	}
+	return types.RuleID(ruleID), nil
+}
+
+// disableRuleForCluster disables rule for given cluster, so the rule is not
+// part of the cluster report
+func (server *HTTPServer) disableRuleForCluster(writer http.ResponseWriter, request *http.Request) {
+	server.toggleRuleForCluster(writer, request, storage.RuleToggleDisable)
+}
+
+// enableRuleForCluster re-enables rule for given cluster
+func (server *HTTPServer) enableRuleForCluster(writer http.ResponseWriter, request *http.Request) {
+	server.toggleRuleForCluster(writer, request, storage.RuleToggleEnable)
+}
+
+// toggleRuleForCluster disables or enables rule for given cluster
+func (server *HTTPServer) toggleRuleForCluster(
+	writer http.ResponseWriter, request *http.Request, toggle storage.RuleToggle,
+) {
+	clusterName, err := readClusterName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	ruleID, err := readRuleID(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	err = server.Storage.ToggleRuleForCluster(clusterName, ruleID, mockUserID, toggle)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to toggle rule for cluster")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Info().
+		Str("cluster", string(clusterName)).
+		Str("rule", string(ruleID)).
+		Bool("disabled", toggle == storage.RuleToggleDisable).
+		Msg("Rule toggled for cluster")
+
+	err = responses.SendOK(writer, responses.BuildOkResponse())
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
+
+// listDisabledRulesForCluster returns all rules disabled for given cluster
+func (server *HTTPServer) listDisabledRulesForCluster(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	rules, err := server.Storage.ListDisabledRulesForCluster(clusterName, mockUserID)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to read disabled rules for cluster")
+		writeError(writer, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	err = responses.SendOK(writer, responses.BuildOkResponseWithData("rules", rules))
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
@@ -21,8 +21,13 @@ package server
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	// we just have to import this package in order to expose pprof interface in debug mode
 	// disable "G108 (CWE-): Profiling endpoint is automatically exposed on /debug/pprof"
@@ -32,26 +37,101 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
+	"github.com/RedHatInsights/insights-results-aggregator-mock/clock"
 	"github.com/RedHatInsights/insights-results-aggregator-mock/groups"
 	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
 )
 
+// shutdownTimeout is the time given to the server to finish all pending
+// requests when the shutdown is triggered via exit endpoint
+const shutdownTimeout = 5 * time.Second
+
+// unixSocketPrefix is used in server address to specify that Unix domain
+// socket should be used instead of TCP, for example unix:/tmp/mock.sock
+const unixSocketPrefix = "unix:"
+
+// DefaultMaxRequestBodySize is the maximum size of request body (in bytes)
+// used when the limit is not set in configuration
+const DefaultMaxRequestBodySize = 10 * 1024 * 1024
+
+// DefaultMaxClustersPerRequest is the maximum number of clusters returned in
+// one response used when the limit is not set in configuration
+const DefaultMaxClustersPerRequest = 100
+
 // HTTPServer in an implementation of Server interface
 type HTTPServer struct {
 	Config  Configuration
 	Storage storage.Storage
 	Groups  map[string]groups.Group
 	Serv    *http.Server
+	// ExitCode contains process exit code requested via exit endpoint
+	ExitCode int
+	stopping sync.WaitGroup
+	// Clock provides current time for handlers and storage
+	Clock clock.Clock
+	// TimestampClock provides time used in generated timestamps, it is
+	// Clock shifted by configured clock skew
+	TimestampClock clock.Clock
+	// orgFailures injects failures into reports for selected organizations
+	orgFailures *orgFailureInjector
+	// tracerProvider exports spans to OTLP collector, it is nil when
+	// tracing is disabled
+	tracerProvider *sdktrace.TracerProvider
+}
+
+// New constructs new implementation of Server interface. The server uses the
+// same clocks as the storage, see StorageOptions. Tracer provider is created
+// here once, it is shut down by Stop.
+func New(config Configuration, dataStorage storage.Storage, groups map[string]groups.Group) *HTTPServer {
+	server := &HTTPServer{
+		Config:         config,
+		Storage:        dataStorage,
+		Groups:         groups,
+		Clock:          dataStorage.Clock(),
+		TimestampClock: dataStorage.TimestampClock(),
+		orgFailures:    newOrgFailureInjector(config),
+	}
+
+	if config.Tracing {
+		tracerProvider, err := newTracerProvider(config)
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to initialize tracing, spans won't be exported")
+		}
+		server.tracerProvider = tracerProvider
+	}
+	return server
+}
+
+// StorageOptions returns settings of memory storage derived from server
+// configuration. Mock clock is used when frozen time is set in
+// configuration, otherwise real time is used.
+func StorageOptions(config Configuration) storage.Options {
+	storageClock := newClock(config)
+	return storage.Options{
+		Clock:                  storageClock,
+		TimestampClock:         clock.NewSkewedClock(storageClock, config.ClockSkew),
+		SyntheticClustersOrgID: config.SyntheticClustersOrgID,
+		SyntheticClustersCount: config.SyntheticClustersCount,
+		RuleTimestamps:         config.RuleTimestamps,
+		DeletedClusters:        config.DeletedClusters,
+	}
 }
 
-// New constructs new implementation of Server interface
-func New(config Configuration, storage storage.Storage, groups map[string]groups.Group) *HTTPServer {
-	return &HTTPServer{
-		Config:  config,
-		Storage: storage,
-		Groups:  groups,
+// newClock constructs clock according to the configuration
+func newClock(config Configuration) clock.Clock {
+	if config.FrozenTime == "" {
+		return clock.RealClock{}
 	}
+
+	frozenTime, err := time.Parse(time.RFC3339, config.FrozenTime)
+	if err != nil {
+		log.Error().Err(err).Msg("Improper frozen time, real time will be used")
+		return clock.RealClock{}
+	}
+
+	return clock.NewMockClock(frozenTime)
 }
 
 // Start starts server
@@ -60,19 +140,125 @@ func (server *HTTPServer) Start() error {
 	log.Info().Msgf("Starting HTTP server at '%s'", address)
 	router := server.Initialize(address)
 	server.Serv = &http.Server{Addr: address, Handler: router}
+	server.printAccessInfo()
 
-	err := server.Serv.ListenAndServe()
+	listener, err := server.listen()
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to create listener")
+		return err
+	}
+
+	stopReloading := server.reloadOnSignal()
+	defer stopReloading()
+
+	if server.Config.TLS {
+		err = server.serveTLS(listener)
+	} else {
+		err = server.Serv.Serve(listener)
+	}
 	if err != nil && err != http.ErrServerClosed {
 		log.Error().Err(err).Msg("Unable to start HTTP/S server")
 		return err
 	}
 
+	// Serve returns immediately when shutdown is initiated, so we need to
+	// wait for all pending requests to be finished
+	server.stopping.Wait()
+
 	return nil
 }
 
 // Stop stops server's execution
 func (server *HTTPServer) Stop(ctx context.Context) error {
-	return server.Serv.Shutdown(ctx)
+	server.stopping.Add(1)
+	defer server.stopping.Done()
+
+	err := server.Serv.Shutdown(ctx)
+
+	if server.tracerProvider != nil {
+		exportErr := server.tracerProvider.Shutdown(ctx)
+		if exportErr != nil {
+			log.Error().Err(exportErr).Msg("Unable to export remaining spans")
+		}
+	}
+
+	if socketPath, isSocket := server.unixSocketPath(); isSocket {
+		removeErr := os.Remove(socketPath)
+		if removeErr != nil && !os.IsNotExist(removeErr) {
+			log.Error().Err(removeErr).Msg("Unable to remove Unix domain socket")
+		}
+	}
+
+	return err
+}
+
+// unixSocketPath returns path to Unix domain socket if the server address
+// is specified in form unix:/path/to.sock
+func (server *HTTPServer) unixSocketPath() (string, bool) {
+	address := server.Config.Address
+	if !strings.HasPrefix(address, unixSocketPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(address, unixSocketPrefix), true
+}
+
+// listen creates listener either for Unix domain socket or for TCP address
+func (server *HTTPServer) listen() (net.Listener, error) {
+	if socketPath, isSocket := server.unixSocketPath(); isSocket {
+		// socket file might be left by previous (killed) instance
+		err := os.Remove(socketPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		log.Info().Str("path", socketPath).Msg("Listening on Unix domain socket")
+		return net.Listen("unix", socketPath)
+	}
+
+	address := server.Config.Address
+	if address == "" {
+		// the same default as used by ListenAndServe/ListenAndServeTLS
+		address = ":" + server.scheme()
+	}
+	return net.Listen("tcp", address)
+}
+
+// scheme returns URL scheme used by the server
+func (server *HTTPServer) scheme() string {
+	if server.Config.TLS {
+		return "https"
+	}
+	return "http"
+}
+
+// printAccessInfo prints a hint how to access the service
+func (server *HTTPServer) printAccessInfo() {
+	if socketPath, isSocket := server.unixSocketPath(); isSocket {
+		url := server.scheme() + "://localhost" + server.Config.APIPrefix
+		log.Info().Msgf("Service is accessible by: curl -k -v --unix-socket %s %s", socketPath, url)
+		return
+	}
+
+	host := server.Config.Address
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	url := server.scheme() + "://" + host + server.Config.APIPrefix
+	log.Info().Msgf("Service is accessible by: curl -k -v %s", url)
+}
+
+// exit stops server's execution and sets the exit code that should be
+// returned by the service process
+func (server *HTTPServer) exit(code int) {
+	log.Info().Int("exit code", code).Msg("Stopping HTTP server")
+	server.ExitCode = code
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := server.Stop(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to stop HTTP server gracefully")
+	}
 }
 
 // Initialize perform the server initialization
@@ -81,42 +267,271 @@ func (server *HTTPServer) Initialize(address string) http.Handler {
 
 	router := mux.NewRouter().StrictSlash(true)
 
+	// registered first, so spans cover time spent in all other middlewares
+	if server.tracerProvider != nil {
+		router.Use(server.newTracingMiddleware())
+	}
+
+	router.Use(server.limitRequestBodySize)
+
+	// registered before timeout and latency middlewares, so delayed requests
+	// are counted as being processed
+	if server.Config.MaxConcurrentRequests > 0 {
+		router.Use(server.newConcurrencyLimitMiddleware())
+	}
+
+	// registered before other middlewares, so injected latency counts into
+	// the timeout as well
+	if server.Config.RequestTimeout > 0 {
+		router.Use(server.newTimeoutMiddleware())
+	}
+
+	if len(server.Config.AllowedOrigins) > 0 {
+		router.Use(server.addCORSHeaders)
+	}
+
+	if server.Config.LatencyDistribution != "" {
+		latency, err := server.newLatencyMiddleware()
+		if err != nil {
+			log.Error().Err(err).Msg("Improper latency configuration, responses won't be delayed")
+		} else {
+			router.Use(latency)
+		}
+	}
+
+	if server.Config.ChaosProbability > 0 {
+		router.Use(server.newChaosMiddleware())
+	}
+
+	// failures requested by headers are allowed in debug mode only
+	if server.Config.Debug {
+		router.Use(server.injectRequestedFaults)
+	}
+
+	if server.Config.ValidateRequests {
+		validator, err := server.newRequestValidationMiddleware()
+		if err != nil {
+			log.Error().Err(err).Msg("Unable to load OpenAPI specification, requests won't be validated")
+		} else {
+			router.Use(validator)
+		}
+	}
+
 	server.addEndpointsToRouter(router)
+	router.MethodNotAllowedHandler = newMethodNotAllowedHandler(router)
+	router.NotFoundHandler = http.HandlerFunc(notFoundHandler)
 	log.Info().Msgf("Server has been initiliazed")
 
-	return router
+	// router middlewares are not used for unknown endpoints, so the whole
+	// router is wrapped to add headers to all responses
+	var handler http.Handler = router
+	if len(server.Config.ResponseHeaders) > 0 {
+		handler = server.addResponseHeaders(handler)
+	}
+
+	// path needs to be changed before the router tries to match it
+	if strings.Trim(server.Config.StripPrefix, "/") != "" {
+		handler = server.stripPathPrefix(handler)
+	}
+	return handler
+}
+
+// addResponseHeaders - middleware for adding configured static headers to
+// all responses. The headers are set before the request is handled, so
+// headers set by handlers take precedence.
+func (server *HTTPServer) addResponseHeaders(nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			for name, value := range server.Config.ResponseHeaders {
+				w.Header().Set(name, value)
+			}
+			nextHandler.ServeHTTP(w, r)
+		})
 }
 
-func (server *HTTPServer) addEndpointsToRouter(router *mux.Router) {
+// stripPathPrefix - middleware removing prefix specified by StripPrefix
+// from request path, so requests forwarded by proxy that does not strip the
+// prefix are routed as well. Requests without the prefix are passed as is.
+func (server *HTTPServer) stripPathPrefix(nextHandler http.Handler) http.Handler {
+	prefix := "/" + strings.Trim(server.Config.StripPrefix, "/")
+
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.Path
+			if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+				nextHandler.ServeHTTP(w, r)
+				return
+			}
+
+			stripped := r.Clone(r.Context())
+			stripped.URL.Path = trimPathPrefix(path, prefix)
+			// escaped path contains the same (unescaped) prefix
+			if r.URL.RawPath != "" {
+				stripped.URL.RawPath = trimPathPrefix(r.URL.RawPath, prefix)
+			}
+			nextHandler.ServeHTTP(w, stripped)
+		})
+}
+
+// trimPathPrefix removes prefix from URL path, the result always starts with
+// slash
+func trimPathPrefix(path, prefix string) string {
+	return "/" + strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+}
+
+// apiPrefix returns API prefix from configuration that always ends with slash
+func (server *HTTPServer) apiPrefix() string {
 	apiPrefix := server.Config.APIPrefix
 	if !strings.HasSuffix(apiPrefix, "/") {
 		apiPrefix += "/"
 	}
+	return apiPrefix
+}
+
+func (server *HTTPServer) addEndpointsToRouter(router *mux.Router) {
+	apiPrefix := server.apiPrefix()
 	log.Info().Msgf("API prefix is set to '%s'", apiPrefix)
 
 	openAPIURL := apiPrefix + filepath.Base(server.Config.APISpecFile)
 
 	// common REST API endpoints
 	router.HandleFunc(apiPrefix+MainEndpoint, server.mainEndpoint).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+InfoEndpoint, server.serviceInfo).Methods(http.MethodGet)
 	router.HandleFunc(apiPrefix+GroupsEndpoint, server.listOfGroups).Methods(http.MethodGet, http.MethodOptions)
 
 	router.HandleFunc(apiPrefix+OrganizationsEndpoint, server.listOfOrganizations).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+OrganizationsCountsEndpoint, server.countsOfClustersForOrganizations).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+OrganizationsAccessEndpoint, server.accessToOrganizations).Methods(http.MethodGet)
 	router.HandleFunc(apiPrefix+ClustersForOrganizationEndpoint, server.listOfClustersForOrganization).Methods(http.MethodGet)
-	router.HandleFunc(apiPrefix+ReportEndpoint, server.readReportForOrganizationAndCluster).Methods(http.MethodGet, http.MethodOptions)
-	router.HandleFunc(apiPrefix+ReportForClusterEndpoint, server.readReportForCluster).Methods(http.MethodGet, http.MethodOptions)
+	router.HandleFunc(apiPrefix+OrganizationStatsEndpoint, server.ruleHitStatsForOrganization).Methods(http.MethodGet)
+	// needs to be registered before ReportEndpoint that would match as well
+	router.HandleFunc(apiPrefix+ReportStreamEndpoint, server.streamReportForCluster).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+ReportSummaryEndpoint, server.readReportSummaryForCluster).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+RulesHitByClusterEndpoint, server.readRulesHitByCluster).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+ReportHistoryEndpoint, server.readReportHistory).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+RuleHitsByCategoryEndpoint, server.readRuleHitsByCategory).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+ReportSnapshotEndpoint, server.readReportSnapshot).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+ReportsWebSocketEndpoint, server.subscribeToReports).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+ReportEndpoint, server.readReportForOrganizationAndCluster).Methods(http.MethodGet, http.MethodHead, http.MethodOptions)
+	// needs to be registered before report/{cluster}
+	router.HandleFunc(apiPrefix+ReportSchemaEndpoint, server.reportSchema).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+ReportForClusterEndpoint, server.readReportForCluster).Methods(http.MethodGet, http.MethodHead, http.MethodOptions)
+	router.HandleFunc(apiPrefix+ClustersByPatternEndpoint, server.readReportForClustersByPattern).Methods(http.MethodGet)
 	router.HandleFunc(apiPrefix+ClustersEndpoint, server.readReportForClusters).Methods(http.MethodGet, http.MethodPost, http.MethodOptions)
+	router.HandleFunc(apiPrefix+MultiReportsEndpoint, server.readReportsForOrganizationsAndClusters).Methods(http.MethodPost)
 	router.HandleFunc(apiPrefix+ClustersInOrgEndpoint, server.readReportForAllClustersInOrg).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+DisableRuleForClusterEndpoint, server.disableRuleForCluster).Methods(http.MethodPut, http.MethodPost)
+	router.HandleFunc(apiPrefix+EnableRuleForClusterEndpoint, server.enableRuleForCluster).Methods(http.MethodPut, http.MethodPost)
+	router.HandleFunc(apiPrefix+DisabledRulesForClusterEndpoint, server.listDisabledRulesForCluster).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+RulesByTagEndpoint, server.listOfRulesWithTag).Methods(http.MethodGet)
+	router.HandleFunc(apiPrefix+RuleFrequencyEndpoint, server.ruleHitFrequency).Methods(http.MethodGet)
+	// needs to be registered after other content endpoints
+	router.HandleFunc(apiPrefix+RuleContentEndpoint, server.readRuleContent).Methods(http.MethodGet)
 	router.HandleFunc(apiPrefix+RuleClusterDetailEndpoint, server.ruleClusterDetailEndpoint).Methods(http.MethodGet)
 
 	// OpenAPI specs
 	router.HandleFunc(openAPIURL, server.serveAPISpecFile).Methods(http.MethodGet)
+
+	if server.Config.Debug {
+		server.addDebugEndpointsToRouter(router, apiPrefix)
+	}
+}
+
+// addDebugEndpointsToRouter method adds endpoints that should be available
+// in debug mode only
+func (server *HTTPServer) addDebugEndpointsToRouter(router *mux.Router, apiPrefix string) {
+	log.Info().Msg("Debug endpoints are enabled")
+
+	// all debug endpoints are registered into separate subrouter so they
+	// can be protected by credentials without affecting other endpoints
+	debugRouter := router.NewRoute().Subrouter()
+	if server.Config.DebugUsername != "" {
+		log.Info().Msg("Debug endpoints are protected by basic auth")
+		debugRouter.Use(server.debugAuthMiddleware)
+	}
+
+	debugRouter.HandleFunc(apiPrefix+ExitEndpoint, server.exitEndpoint).Methods(http.MethodPut)
+	debugRouter.HandleFunc(apiPrefix+ChangingClusterEndpoint, server.changingClusterVariant).Methods(http.MethodGet)
+	debugRouter.HandleFunc(apiPrefix+DumpEndpoint, server.dumpStorage).Methods(http.MethodGet)
+	debugRouter.HandleFunc(apiPrefix+LoadedFilesEndpoint, server.listLoadedFiles).Methods(http.MethodGet)
+	debugRouter.HandleFunc(apiPrefix+ReloadEndpoint, server.reloadStorage).Methods(http.MethodPost)
+	debugRouter.HandleFunc(apiPrefix+ResetEndpoint, server.resetState).Methods(http.MethodPost)
+	debugRouter.HandleFunc(apiPrefix+DenyOrganizationEndpoint, server.denyOrganization).Methods(http.MethodPut, http.MethodPost)
+	debugRouter.HandleFunc(apiPrefix+DeleteClusterEndpoint, server.deleteCluster).Methods(http.MethodPut, http.MethodPost)
+	debugRouter.HandleFunc(apiPrefix+ConfigEndpoint, server.effectiveConfiguration).Methods(http.MethodGet)
+	debugRouter.HandleFunc(apiPrefix+ReportDiffEndpoint, server.diffReports).Methods(http.MethodGet)
+	debugRouter.HandleFunc(apiPrefix+DebugReportEndpoint, server.patchReport).Methods(http.MethodPatch)
+	debugRouter.HandleFunc(apiPrefix+DebugReportEndpoint, server.deleteReport).Methods(http.MethodDelete)
+
+	// time can be moved only when mock clock is used
+	if _, ok := server.Clock.(*clock.MockClock); ok {
+		debugRouter.HandleFunc(apiPrefix+AdvanceClockEndpoint, server.advanceClock).Methods(http.MethodPost)
+	}
+}
+
+// maxClustersPerRequest returns the limit for number of clusters returned in
+// one response
+func (server *HTTPServer) maxClustersPerRequest() int {
+	if server.Config.MaxClustersPerRequest > 0 {
+		return server.Config.MaxClustersPerRequest
+	}
+	return DefaultMaxClustersPerRequest
 }
 
-// addCORSHeaders - middleware for adding headers that should be in any response
+// maxRequestBodySize returns the limit for request body size
+func (server *HTTPServer) maxRequestBodySize() int64 {
+	if server.Config.MaxRequestBodySize > 0 {
+		return server.Config.MaxRequestBodySize
+	}
+	return DefaultMaxRequestBodySize
+}
+
+// limitRequestBodySize - middleware for limiting size of request body for
+// all methods that can have a payload. Connection is closed after response
+// to request with too large body.
+func (server *HTTPServer) limitRequestBodySize(nextHandler http.Handler) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch:
+				r.Body = http.MaxBytesReader(w, r.Body, server.maxRequestBodySize())
+			}
+			nextHandler.ServeHTTP(w, r)
+		})
+}
+
+// isRequestBodyTooLarge checks if given error has been returned because
+// request body exceeds the limit set by limitRequestBodySize
+func isRequestBodyTooLarge(err error) bool {
+	var maxBytesError *http.MaxBytesError
+	return errors.As(err, &maxBytesError)
+}
+
+// isOriginAllowed checks if given origin is in the list of allowed origins
+func (server *HTTPServer) isOriginAllowed(origin string) bool {
+	for _, allowed := range server.Config.AllowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// addCORSHeaders - middleware for adding CORS headers to responses for
+// requests coming from allowed origins
 func (server *HTTPServer) addCORSHeaders(nextHandler http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
+			// response depends on Origin header so caches need to know
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			if origin == "" || !server.isOriginAllowed(origin) {
+				nextHandler.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
 			w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
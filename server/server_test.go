@@ -15,3 +15,2155 @@ limitations under the License.
 */
 
 package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-yaml/yaml"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/clock"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/groups"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/server"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+const (
+	testAPIPrefix       = "/api/v1/"
+	testMockDataPath    = "../data"
+	testMaxBodySize     = 100
+	testExistingCluster = "34c3ecc5-624a-49a5-bab8-4fdc5e51a266"
+)
+
+// newTestServer constructs HTTP server with storage filled by mock data
+func newTestServer(t *testing.T, config server.Configuration) *server.HTTPServer {
+	return newTestServerWithData(t, config, testMockDataPath)
+}
+
+// newTestServerWithData constructs HTTP server with storage filled by data
+// from given directory. Mock data are loaded again when the test finishes.
+func newTestServerWithData(t *testing.T, config server.Configuration, path string) *server.HTTPServer {
+	s, err := storage.New(path, server.StorageOptions(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != testMockDataPath {
+		t.Cleanup(func() {
+			_, err := storage.New(testMockDataPath, storage.Options{})
+			assert.NoError(t, err)
+		})
+	}
+
+	if config.APIPrefix == "" {
+		config.APIPrefix = testAPIPrefix
+	}
+
+	return server.New(config, s, nil)
+}
+
+// newTestDataDir creates data directory with all mock report files and given
+// additional files
+func newTestDataDir(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+
+	writeFile := func(name string, content []byte) {
+		err := ioutil.WriteFile(filepath.Join(dir, name), content, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reports, err := filepath.Glob(filepath.Join(testMockDataPath, "report_*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, report := range reports {
+		content, err := ioutil.ReadFile(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeFile(filepath.Base(report), content)
+	}
+
+	for name, content := range files {
+		writeFile(name, []byte(content))
+	}
+	return dir
+}
+
+// sendRequest sends the request to given server and returns recorded response
+func sendRequest(serv *server.HTTPServer, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	serv.Initialize("").ServeHTTP(recorder, request)
+	return recorder
+}
+
+// TestReadReportForClustersTruncatedBody checks that truncated JSON in request
+// body is refused with 400 Bad Request
+func TestReadReportForClustersTruncatedBody(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{MaxRequestBodySize: testMaxBodySize})
+
+	body := `{"clusters":["` + testExistingCluster
+	request := httptest.NewRequest(http.MethodPost, testAPIPrefix+server.ClustersEndpoint, strings.NewReader(body))
+	response := sendRequest(serv, request)
+
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "malformed request body")
+}
+
+// TestReadReportForClustersOversizedBody checks that request body exceeding
+// configured limit is refused
+func TestReadReportForClustersOversizedBody(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{MaxRequestBodySize: testMaxBodySize})
+
+	clusters := strings.Repeat(`"`+testExistingCluster+`",`, 10)
+	body := `{"clusters":[` + clusters + `"` + testExistingCluster + `"]}`
+	request := httptest.NewRequest(http.MethodPost, testAPIPrefix+server.ClustersEndpoint, strings.NewReader(body))
+	response := sendRequest(serv, request)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.Code)
+}
+
+// TestOversizedBodyClosesConnection checks that connection is closed after
+// response to request with body exceeding configured limit, so the rest of
+// the body is not read by the server
+func TestOversizedBodyClosesConnection(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{MaxRequestBodySize: testMaxBodySize})
+
+	httpServer := httptest.NewServer(serv.Initialize(""))
+	defer httpServer.Close()
+
+	body := `{"clusters":[` + strings.Repeat(`"`+testExistingCluster+`",`, 1000) + `"` + testExistingCluster + `"]}`
+	response, err := http.Post(httpServer.URL+testAPIPrefix+server.ClustersEndpoint, server.ContentTypeJSON, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, response.Body.Close())
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, response.StatusCode)
+	assert.True(t, response.Close)
+}
+
+// TestReadReportForClustersProperBody checks that proper request body is
+// accepted
+func TestReadReportForClustersProperBody(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{MaxRequestBodySize: testMaxBodySize})
+
+	body := `{"clusters":["` + testExistingCluster + `"]}`
+	request := httptest.NewRequest(http.MethodPost, testAPIPrefix+server.ClustersEndpoint, strings.NewReader(body))
+	response := sendRequest(serv, request)
+
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), testExistingCluster)
+}
+
+// TestReadReportForClustersInvalidBody checks that all problems found in
+// list of clusters are reported with indexes of improper entries
+func TestReadReportForClustersInvalidBody(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	body := `{"clusters":["` + testExistingCluster + `", 42, "not-a-cluster"]}`
+	request := httptest.NewRequest(http.MethodPost, testAPIPrefix+server.ClustersEndpoint, strings.NewReader(body))
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	var result struct {
+		Errors []server.ValidationError `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &result))
+	assert.Len(t, result.Errors, 2)
+	assert.Equal(t, 1, *result.Errors[0].Index)
+	assert.Equal(t, 2, *result.Errors[1].Index)
+
+	request = httptest.NewRequest(http.MethodPost, testAPIPrefix+server.ClustersEndpoint, strings.NewReader(`{}`))
+	response = sendRequest(serv, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+	assert.Contains(t, response.Body.String(), "clusters field is required")
+}
+
+// TestRequestValidationAgainstAPISpec checks that request not conforming to
+// OpenAPI specification is refused when validation is enabled
+func TestRequestValidationAgainstAPISpec(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{
+		APISpecFile:      "../openapi.json",
+		ValidateRequests: true,
+	})
+
+	request := httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/not-a-number/"+testExistingCluster, nil)
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	request = httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/11789772/"+testExistingCluster, nil)
+	response = sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+// TestCORSHeadersForAllowedOrigin checks that allowed origin is echoed back
+// and that no CORS headers are sent for other origins
+func TestCORSHeadersForAllowedOrigin(t *testing.T) {
+	const allowedOrigin = "https://console.example.com"
+	serv := newTestServer(t, server.Configuration{
+		AllowedOrigins: []string{allowedOrigin},
+	})
+
+	request := httptest.NewRequest(http.MethodGet, testAPIPrefix+server.OrganizationsEndpoint, nil)
+	request.Header.Set("Origin", allowedOrigin)
+	response := sendRequest(serv, request)
+	assert.Equal(t, allowedOrigin, response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", response.Header().Get("Access-Control-Allow-Credentials"))
+
+	request = httptest.NewRequest(http.MethodGet, testAPIPrefix+server.OrganizationsEndpoint, nil)
+	request.Header.Set("Origin", "https://evil.example.com")
+	response = sendRequest(serv, request)
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, response.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+// TestHeadRequestForReports checks that HEAD request returns Content-Length
+// of the report without the body itself
+func TestHeadRequestForReports(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	for _, url := range []string{
+		testAPIPrefix + "report/" + testExistingCluster,
+		testAPIPrefix + "report/11789772/" + testExistingCluster,
+	} {
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+		expectedLength := strconv.Itoa(response.Body.Len())
+
+		response = sendRequest(serv, httptest.NewRequest(http.MethodHead, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, expectedLength, response.Header().Get("Content-Length"))
+		assert.Zero(t, response.Body.Len())
+	}
+}
+
+// TestReadReportForClusterFromOtherOrganization checks that report for cluster
+// owned by another organization is refused with 403 Forbidden
+func TestReadReportForClusterFromOtherOrganization(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/1/"+testExistingCluster, nil))
+	assert.Equal(t, http.StatusForbidden, response.Code)
+}
+
+// TestReadReportForSlowCluster checks that report for "slow cluster" is
+// accepted first and returned after the configured delay
+func TestReadReportForSlowCluster(t *testing.T) {
+	const delay = 30 * time.Second
+	serv := newTestServer(t, server.Configuration{
+		Debug:            true,
+		SlowClusterDelay: delay,
+		FrozenTime:       "2021-01-01T12:00:00Z",
+	})
+	url := testAPIPrefix + "report/aaaaaaaa-aaaa-aaaa-aaaa-000000000001"
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusAccepted, response.Code)
+	assert.Equal(t, "30", response.Header().Get("Retry-After"))
+
+	serv.Clock.(*clock.MockClock).Advance(delay)
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"debug/reset", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusAccepted, response.Code)
+}
+
+// TestReadReportForClustersErrorDetails checks that reason of failure is
+// provided for each cluster whose report can't be returned
+func TestReadReportForClustersErrorDetails(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{ReportErrorDetails: true})
+	serv.Storage.SetOrganizationDenied(1, true)
+	defer serv.Storage.ResetDeniedOrganizations()
+
+	body := `{"clusters": ["` + testExistingCluster + `", "00000000-0000-0000-0000-000000000000", "00000001-624a-49a5-bab8-4fdc5e51a266"]}`
+	response := sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"clusters", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var reports server.ClusterReports
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &reports))
+	assert.Equal(t, []types.ClusterName{testExistingCluster}, reports.ClusterList)
+	assert.Equal(t, []types.ClusterName{"00000000-0000-0000-0000-000000000000", "00000001-624a-49a5-bab8-4fdc5e51a266"}, reports.Errors)
+	assert.Equal(t, []server.ClusterError{
+		{Cluster: "00000000-0000-0000-0000-000000000000", Reason: server.ReportErrorNotFound},
+		{Cluster: "00000001-624a-49a5-bab8-4fdc5e51a266", Reason: server.ReportErrorForbidden},
+	}, reports.ErrorDetails)
+
+	// only flat list of errors is returned by default
+	serv = newTestServer(t, server.Configuration{})
+	response = sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"clusters", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.NotContains(t, response.Body.String(), "error_details")
+}
+
+// TestReadReportForClustersStillProcessing checks that newly seen clusters
+// are reported as processing until the delay expires
+func TestReadReportForClustersStillProcessing(t *testing.T) {
+	const delay = time.Minute
+	serv := newTestServer(t, server.Configuration{
+		BatchProcessingDelay: delay,
+		FrozenTime:           "2021-01-01T12:00:00Z",
+	})
+	serv.Storage.ResetSlowClusters()
+	defer serv.Storage.ResetSlowClusters()
+
+	body := `{"clusters": ["` + testExistingCluster + `", "00000000-0000-0000-0000-000000000000"]}`
+	response := sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"clusters", strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var reports server.ClusterReports
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &reports))
+	assert.Equal(t, []types.ClusterName{testExistingCluster}, reports.Processing)
+	assert.Equal(t, []types.ClusterName{"00000000-0000-0000-0000-000000000000"}, reports.Errors)
+	assert.Empty(t, reports.Reports)
+
+	serv.Clock.(*clock.MockClock).Advance(delay)
+	response = sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"clusters", strings.NewReader(body)))
+	reports = server.ClusterReports{}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &reports))
+	assert.Empty(t, reports.Processing)
+	assert.Contains(t, reports.Reports, types.ClusterName(testExistingCluster))
+}
+
+// TestReadEmptyReport checks that rule hits are removed from report when
+// requested
+func TestReadEmptyReport(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+	url := testAPIPrefix + "report/" + testExistingCluster
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url+"?empty=true", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"data": []`)
+	assert.Contains(t, response.Body.String(), `"count": 0`)
+	assert.Contains(t, response.Body.String(), `"last_checked_at"`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, url+"?empty=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestReadReportInLegacyV1Schema checks that report is converted into
+// legacy v1 schema when requested via Accept header
+func TestReadReportInLegacyV1Schema(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	request := httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+testExistingCluster, nil)
+	request.Header.Set("Accept", "application/json; profile=v1")
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var report map[string]interface{}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+	assert.IsType(t, []interface{}{}, report["reports"])
+	assert.Contains(t, report, "last_checked_at")
+}
+
+// TestListOfRulesWithTag checks that rules are filtered by tag
+func TestListOfRulesWithTag(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"content/tags/security", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"security"`)
+	assert.NotContains(t, response.Body.String(), `"count":0`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"content/tags/Security", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"count": 0`)
+	assert.Contains(t, response.Body.String(), `"rules": []`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"content/tags/Security?ignoreCase=true", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.NotContains(t, response.Body.String(), `"count":0`)
+}
+
+// TestReadPrecompressedReport checks that precompressed report is sent when
+// client accepts gzip encoding and when the report is not transformed
+func TestReadPrecompressedReport(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+	url := testAPIPrefix + "report/" + testExistingCluster
+
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "gzip", response.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(response.Body)
+	assert.NoError(t, err)
+	report, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.True(t, json.Valid(report))
+
+	request = httptest.NewRequest(http.MethodGet, url+"?empty=true", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	response = sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Get("Content-Encoding"))
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Empty(t, response.Header().Get("Content-Encoding"))
+}
+
+// TestReadReportSummary checks that summary is returned for existing cluster
+// and 404 Not Found for cluster without report
+func TestReadReportSummary(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+testExistingCluster+"/summary", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"rule_hits": 7`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/12345678-0000-0000-0000-000000000000/summary", nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+// TestPatchReport checks that JSON Merge Patch is applied to stored report
+func TestPatchReport(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{Debug: true})
+	url := testAPIPrefix + "debug/report/" + testExistingCluster
+
+	request := httptest.NewRequest(http.MethodPatch, url, strings.NewReader(`{"reports": {"meta": {"last_checked_at": "2021-01-01T00:00:00Z"}}}`))
+	request.Header.Set("Content-Type", server.ContentTypeMergePatch)
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+testExistingCluster+"/summary", nil))
+	assert.Contains(t, response.Body.String(), `"last_checked_at": "2021-01-01T00:00:00Z"`)
+
+	request = httptest.NewRequest(http.MethodPatch, testAPIPrefix+"debug/report/12345678-0000-0000-0000-000000000000", strings.NewReader(`{}`))
+	request.Header.Set("Content-Type", server.ContentTypeMergePatch)
+	response = sendRequest(serv, request)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+// TestReadReportForClusterWithDefaultOrganization checks that organization
+// permissions are checked by report/{cluster} endpoint when default
+// organization is configured
+func TestReadReportForClusterWithDefaultOrganization(t *testing.T) {
+	url := testAPIPrefix + "report/" + testExistingCluster
+
+	serv := newTestServer(t, server.Configuration{DefaultOrgID: 11789772})
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	serv = newTestServer(t, server.Configuration{DefaultOrgID: 11940171})
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusForbidden, response.Code)
+}
+
+// TestReadReportForMixedCaseClusterName checks that cluster name is
+// normalized before report lookup and that improper names are refused
+func TestReadReportForMixedCaseClusterName(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+	mixedCaseCluster := "34C3ECC5-624a-49A5-bab8-4FDC5E51A266"
+
+	for _, url := range []string{
+		testAPIPrefix + "report/" + mixedCaseCluster,
+		testAPIPrefix + "report/11789772/" + mixedCaseCluster,
+	} {
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"reports"`)
+	}
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/not-a-cluster", nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestGeneratedAtWithFrozenTime checks that configured frozen time is used
+// as generated_at value
+func TestGeneratedAtWithFrozenTime(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{FrozenTime: "2021-01-01T12:00:00Z"})
+
+	body := strings.NewReader(`{"clusters": ["` + testExistingCluster + `"]}`)
+	response := sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"clusters", body))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"generated_at": "2021-01-01T12:00:00Z"`)
+}
+
+// TestGeneratedAtWithClockSkew checks that configured clock skew is added to
+// generated_at value
+func TestGeneratedAtWithClockSkew(t *testing.T) {
+	expected := map[time.Duration]string{
+		5 * time.Minute:  "2021-01-01T12:05:00Z",
+		-5 * time.Minute: "2021-01-01T11:55:00Z",
+	}
+
+	for skew, generatedAt := range expected {
+		serv := newTestServer(t, server.Configuration{
+			FrozenTime: "2021-01-01T12:00:00Z",
+			ClockSkew:  skew,
+		})
+
+		body := strings.NewReader(`{"clusters": ["` + testExistingCluster + `"]}`)
+		response := sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"clusters", body))
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(t, response.Body.String(), `"generated_at": "`+generatedAt+`"`)
+	}
+}
+
+// TestAdvanceClock checks that mock clock can be advanced via debug endpoint
+func TestAdvanceClock(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{Debug: true, FrozenTime: "2021-01-01T12:00:00Z"})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"debug/clock/advance?by=30m", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"now":"2021-01-01T12:30:00Z"`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"debug/clock/advance?by=-1h", nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	serv = newTestServer(t, server.Configuration{Debug: true})
+	response = sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"debug/clock/advance?by=30m", nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+// TestDebugEndpointsBasicAuth checks that debug endpoints require
+// credentials when configured while other endpoints stay open
+func TestDebugEndpointsBasicAuth(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{Debug: true, DebugUsername: "admin", DebugPassword: "secret"})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"debug/dump", nil))
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.NotEmpty(t, response.Header().Get("WWW-Authenticate"))
+
+	request := httptest.NewRequest(http.MethodGet, testAPIPrefix+"debug/dump", nil)
+	request.SetBasicAuth("admin", "wrong")
+	response = sendRequest(serv, request)
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+
+	request = httptest.NewRequest(http.MethodGet, testAPIPrefix+"debug/dump", nil)
+	request.SetBasicAuth("admin", "secret")
+	response = sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+// TestListLoadedFiles checks that loaded report files are listed
+func TestListLoadedFiles(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{Debug: true})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"debug/files", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"name":"report_`+testExistingCluster+`.json"`)
+	assert.Contains(t, response.Body.String(), `"mtime":`)
+}
+
+// TestListOfClustersForUnknownOrganization checks that unknown organization
+// is distinguished from organization without clusters
+func TestListOfClustersForUnknownOrganization(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/99/clusters", nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/5/clusters", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"clusters":[]`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/11940171/clusters", nil))
+	assert.Equal(t, http.StatusForbidden, response.Code)
+}
+
+// TestCountsOfClustersForOrganizations checks that number of clusters is
+// returned for accessible organizations only
+func TestCountsOfClustersForOrganizations(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/counts", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"2":3`)
+	assert.Contains(t, response.Body.String(), `"5":0`)
+	assert.Contains(t, response.Body.String(), `"11940171":"denied"`)
+}
+
+// TestReadReportsForOrganizationsAndClusters checks that reports for
+// several organizations are returned with status for each item
+func TestReadReportsForOrganizationsAndClusters(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{MaxClustersPerRequest: 3})
+
+	body := strings.NewReader(`[
+		{"org_id": 11789772, "cluster": "` + testExistingCluster + `"},
+		{"org_id": 11940171, "cluster": "` + testExistingCluster + `"},
+		{"org_id": 2, "cluster": "` + testExistingCluster + `"}
+	]`)
+	response := sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"reports/multi", body))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var multiReports server.MultiReports
+	err := json.Unmarshal(response.Body.Bytes(), &multiReports)
+	assert.NoError(t, err)
+	assert.Len(t, multiReports.Reports, 3)
+
+	assert.Equal(t, http.StatusOK, multiReports.Reports[0].Status)
+	assert.NotNil(t, multiReports.Reports[0].Report)
+	assert.Equal(t, http.StatusForbidden, multiReports.Reports[1].Status)
+	assert.Nil(t, multiReports.Reports[1].Report)
+	// cluster is not owned by the organization
+	assert.Equal(t, http.StatusForbidden, multiReports.Reports[2].Status)
+
+	body = strings.NewReader(`[{}, {}, {}, {}]`)
+	response = sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"reports/multi", body))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestReadReportWithOrderedRuleHits checks that rule hits in report can be
+// sorted by rule ID or by total risk
+func TestReadReportWithOrderedRuleHits(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	readRuleIDs := func(orderBy string) []string {
+		url := testAPIPrefix + "report/" + testExistingCluster + "?order_by=" + orderBy
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var payload struct {
+			Reports struct {
+				Data []struct {
+					RuleID string `json:"rule_id"`
+				} `json:"data"`
+			} `json:"reports"`
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &payload)
+		assert.NoError(t, err)
+
+		ruleIDs := []string{}
+		for _, hit := range payload.Reports.Data {
+			ruleIDs = append(ruleIDs, hit.RuleID)
+		}
+		return ruleIDs
+	}
+
+	fileOrder := readRuleIDs("file")
+	assert.Len(t, fileOrder, 7)
+	assert.Equal(t, fileOrder, readRuleIDs(""))
+	assert.False(t, sort.StringsAreSorted(fileOrder))
+
+	byRuleID := readRuleIDs("rule_id")
+	assert.True(t, sort.StringsAreSorted(byRuleID))
+	assert.ElementsMatch(t, fileOrder, byRuleID)
+
+	byTotalRisk := readRuleIDs("total_risk")
+	assert.ElementsMatch(t, fileOrder, byTotalRisk)
+	assert.Equal(t, byTotalRisk, readRuleIDs("total_risk"))
+
+	url := testAPIPrefix + "report/" + testExistingCluster + "?order_by=foo"
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestReportSchema checks that schema version and supported features are
+// returned and that the endpoint does not clash with report for cluster
+func TestReportSchema(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/schema", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var payload struct {
+		Schema server.ReportSchema `json:"schema"`
+		Status string              `json:"status"`
+	}
+	err := json.Unmarshal(response.Body.Bytes(), &payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", payload.Status)
+	assert.Equal(t, server.ReportSchemaVersion, payload.Schema.Version)
+	assert.Contains(t, payload.Schema.Features, server.FeatureLegacyV1Schema)
+}
+
+// TestListOfClustersForOrganizationTruncated checks that list of clusters is
+// truncated to configured size and that total number of clusters is returned
+func TestListOfClustersForOrganizationTruncated(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{MaxClustersInOrgList: 2})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/2/clusters", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var payload struct {
+		Clusters  []string `json:"clusters"`
+		Truncated bool     `json:"truncated"`
+		Total     int      `json:"total"`
+	}
+	err := json.Unmarshal(response.Body.Bytes(), &payload)
+	assert.NoError(t, err)
+	assert.Len(t, payload.Clusters, 2)
+	assert.True(t, payload.Truncated)
+	assert.Equal(t, 3, payload.Total)
+	assert.Equal(t, "true", response.Header().Get("X-Truncated"))
+	assert.Equal(t, "3", response.Header().Get("X-Total-Count"))
+
+	// CSV output can't contain the flag, so only headers are set
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/2/clusters?format=csv", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "true", response.Header().Get("X-Truncated"))
+	assert.Equal(t, "3", response.Header().Get("X-Total-Count"))
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/3/clusters?changed_since=2100-01-01T00:00:00Z", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.NotContains(t, response.Body.String(), "truncated")
+	assert.Empty(t, response.Header().Get("X-Truncated"))
+}
+
+// TestReadRulesHitByCluster checks that list of rules hit by cluster is
+// returned, empty for cluster without rule hits and 404 for unknown cluster
+func TestReadRulesHitByCluster(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	readRules := func(clusterName string) (int, []string) {
+		url := testAPIPrefix + "cluster/" + clusterName + "/rules"
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+
+		var payload struct {
+			Rules []string `json:"rules"`
+		}
+		_ = json.Unmarshal(response.Body.Bytes(), &payload)
+		return response.Code, payload.Rules
+	}
+
+	code, rules := readRules(testExistingCluster)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, rules, 7)
+	assert.Contains(t, rules, "ccx_rules_ocp.external.rules.node_installer_degraded|NODE_INSTALLER_DEGRADED")
+
+	code, rules = readRules("eeeeeeee-eeee-eeee-eeee-000000000001")
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotNil(t, rules)
+	assert.Empty(t, rules)
+
+	code, _ = readRules("00000000-0000-0000-0000-000000000000")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+// TestFaultsRequestedByHeaders checks that status and delay requested by
+// headers are injected in debug mode only
+func TestFaultsRequestedByHeaders(t *testing.T) {
+	newRequest := func(headers map[string]string) *http.Request {
+		request := httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations", nil)
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+		return request
+	}
+
+	serv := newTestServer(t, server.Configuration{Debug: true})
+
+	response := sendRequest(serv, newRequest(map[string]string{"x-mock-status": "503"}))
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.JSONEq(t, `{"status": "Service Unavailable"}`, response.Body.String())
+
+	start := time.Now()
+	response = sendRequest(serv, newRequest(map[string]string{"x-mock-delay": "50"}))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), "organizations")
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	response = sendRequest(serv, newRequest(map[string]string{"x-mock-status": "foo"}))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	serv = newTestServer(t, server.Configuration{Debug: false})
+	response = sendRequest(serv, newRequest(map[string]string{"x-mock-status": "503"}))
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+// TestDenyOrganization checks that access to organization can be denied and
+// allowed at runtime
+func TestDenyOrganization(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{Debug: true})
+	listClusters := func() int {
+		url := testAPIPrefix + "organizations/2/clusters"
+		return sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil)).Code
+	}
+	deny := func(on string) int {
+		url := testAPIPrefix + "debug/org/2/deny?on=" + on
+		return sendRequest(serv, httptest.NewRequest(http.MethodPut, url, nil)).Code
+	}
+
+	assert.Equal(t, http.StatusOK, listClusters())
+
+	assert.Equal(t, http.StatusOK, deny("true"))
+	assert.Equal(t, http.StatusForbidden, listClusters())
+	url := testAPIPrefix + "report/2/00000002-624a-49a5-bab8-4fdc5e51a266"
+	assert.Equal(t, http.StatusForbidden, sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil)).Code)
+
+	assert.Equal(t, http.StatusOK, deny("false"))
+	assert.Equal(t, http.StatusOK, listClusters())
+
+	assert.Equal(t, http.StatusBadRequest, deny("maybe"))
+
+	// reset restores the default set of denied organizations
+	assert.Equal(t, http.StatusOK, deny("true"))
+	sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"debug/reset", nil))
+	assert.Equal(t, http.StatusOK, listClusters())
+}
+
+// TestReadPartialReport checks that the same fraction of rule hits is
+// returned for each request
+func TestReadPartialReport(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	readRuleIDs := func(fraction string) []string {
+		url := testAPIPrefix + "report/" + testExistingCluster + "?fraction=" + fraction
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var payload struct {
+			Reports struct {
+				Meta struct {
+					Count int `json:"count"`
+				} `json:"meta"`
+				Data []struct {
+					RuleID string `json:"rule_id"`
+				} `json:"data"`
+			} `json:"reports"`
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &payload)
+		assert.NoError(t, err)
+		assert.Equal(t, len(payload.Reports.Data), payload.Reports.Meta.Count)
+
+		ruleIDs := []string{}
+		for _, hit := range payload.Reports.Data {
+			ruleIDs = append(ruleIDs, hit.RuleID)
+		}
+		return ruleIDs
+	}
+
+	half := readRuleIDs("0.5")
+	// 3.5 hits are rounded
+	assert.Len(t, half, 4)
+	assert.Equal(t, half, readRuleIDs("0.5"))
+	assert.Subset(t, half, readRuleIDs("0.3"))
+	assert.Len(t, readRuleIDs("1"), 7)
+	assert.Empty(t, readRuleIDs("0"))
+
+	url := testAPIPrefix + "report/" + testExistingCluster + "?fraction=1.5"
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestReadFilteredReport checks that rule hits are filtered by impacting
+// and category query parameters
+func TestReadFilteredReport(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+	serv.Groups = map[string]groups.Group{
+		"security":    {Name: "Security", Tags: []string{"security"}},
+		"performance": {Name: "Performance", Tags: []string{"performance"}},
+	}
+
+	readRuleIDs := func(query string) []string {
+		url := testAPIPrefix + "report/" + testExistingCluster + query
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var payload struct {
+			Reports struct {
+				Meta struct {
+					Count int `json:"count"`
+				} `json:"meta"`
+				Data []struct {
+					RuleID string `json:"rule_id"`
+				} `json:"data"`
+			} `json:"reports"`
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &payload)
+		assert.NoError(t, err)
+		assert.Equal(t, len(payload.Reports.Data), payload.Reports.Meta.Count)
+
+		ruleIDs := []string{}
+		for _, hit := range payload.Reports.Data {
+			ruleIDs = append(ruleIDs, hit.RuleID)
+		}
+		return ruleIDs
+	}
+
+	assert.Len(t, readRuleIDs("?impacting=true"), 7)
+	assert.Empty(t, readRuleIDs("?impacting=false"))
+	assert.Len(t, readRuleIDs("?category=security"), 1)
+	assert.Len(t, readRuleIDs("?category=Performance&impacting=true"), 1)
+	assert.Len(t, readRuleIDs("?unknown=foo"), 7)
+
+	for _, query := range []string{"?impacting=maybe", "?category=unknown"} {
+		url := testAPIPrefix + "report/" + testExistingCluster + query
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusBadRequest, response.Code, query)
+	}
+}
+
+// TestTracing checks that span with trace context propagated from
+// traceparent header is exported to OTLP collector when the server is
+// stopped
+func TestTracing(t *testing.T) {
+	exported := make(chan []byte, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		exported <- body
+	}))
+	defer collector.Close()
+
+	serv := newTestServer(t, server.Configuration{Tracing: true, TracingEndpoint: collector.URL + "/v1/traces"})
+
+	request := httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+testExistingCluster, nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	// remaining spans are exported when the server is stopped
+	serv.Serv = &http.Server{}
+	assert.NoError(t, serv.Stop(context.Background()))
+
+	var payload coltracepb.ExportTraceServiceRequest
+	select {
+	case body := <-exported:
+		assert.NoError(t, proto.Unmarshal(body, &payload))
+	case <-time.After(5 * time.Second):
+		t.Fatal("span has not been exported")
+	}
+
+	span := payload.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(span.TraceId))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(span.ParentSpanId))
+	assert.Equal(t, "GET "+testAPIPrefix+"report/{cluster}", span.Name)
+}
+
+// TestDeletedClusters checks that configured and runtime deleted clusters
+// are treated as if they have no report
+func TestDeletedClusters(t *testing.T) {
+	const deletedCluster = "74ae54aa-6577-4e80-85e7-697cb646ff37"
+
+	serv := newTestServer(t, server.Configuration{
+		Debug:           true,
+		DeletedClusters: []types.ClusterName{deletedCluster},
+	})
+
+	readReport := func(cluster string) int {
+		url := testAPIPrefix + "report/" + cluster
+		return sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil)).Code
+	}
+
+	clustersInOrg := func() []types.ClusterName {
+		url := testAPIPrefix + "organizations/11789772/clusters"
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var payload struct {
+			Clusters []types.ClusterName `json:"clusters"`
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &payload)
+		assert.NoError(t, err)
+		return payload.Clusters
+	}
+
+	setDeleted := func(cluster, on string) {
+		url := testAPIPrefix + "debug/cluster/" + cluster + "/deleted?on=" + on
+		response := sendRequest(serv, httptest.NewRequest(http.MethodPut, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+	}
+
+	assert.Equal(t, http.StatusNotFound, readReport(deletedCluster))
+	assert.NotContains(t, clustersInOrg(), types.ClusterName(deletedCluster))
+
+	setDeleted(testExistingCluster, "true")
+	assert.Equal(t, http.StatusNotFound, readReport(testExistingCluster))
+	assert.NotContains(t, clustersInOrg(), types.ClusterName(testExistingCluster))
+
+	setDeleted(testExistingCluster, "false")
+	assert.Equal(t, http.StatusOK, readReport(testExistingCluster))
+
+	setDeleted(testExistingCluster, "true")
+	response := sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"debug/reset", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, http.StatusOK, readReport(testExistingCluster))
+	assert.Equal(t, http.StatusNotFound, readReport(deletedCluster))
+}
+
+// TestReadReportHistory checks that report snapshots are returned from the
+// most recent one and that their number can be limited
+func TestReadReportHistory(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	readHistory := func(cluster, query string) []storage.ReportSnapshot {
+		url := testAPIPrefix + "cluster/" + cluster + "/history" + query
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var payload struct {
+			History []storage.ReportSnapshot `json:"history"`
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &payload)
+		assert.NoError(t, err)
+		return payload.History
+	}
+
+	history := readHistory(testExistingCluster, "")
+	assert.Len(t, history, 3)
+	assert.Equal(t, "2020-05-27T14:15:35Z", history[0].Timestamp.Format(time.RFC3339))
+	assert.Equal(t, "2020-05-13T10:15:00Z", history[2].Timestamp.Format(time.RFC3339))
+	assert.True(t, history[0].Timestamp.After(history[1].Timestamp))
+
+	limited := readHistory(testExistingCluster, "?limit=2")
+	assert.Len(t, limited, 2)
+	assert.Equal(t, history[0].Timestamp, limited[0].Timestamp)
+
+	assert.Empty(t, readHistory("74ae54aa-6577-4e80-85e7-697cb646ff37", ""))
+
+	url := testAPIPrefix + "cluster/" + testExistingCluster + "/history?limit=-1"
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestReadReportSnapshot checks that snapshot taken at or nearest before
+// given time is returned
+func TestReadReportSnapshot(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	readSnapshot := func(timestamp string) (int, storage.ReportSnapshot) {
+		url := testAPIPrefix + "cluster/" + testExistingCluster + "/history/" + timestamp
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+
+		var payload struct {
+			Snapshot storage.ReportSnapshot `json:"snapshot"`
+		}
+		if response.Code == http.StatusOK {
+			err := json.Unmarshal(response.Body.Bytes(), &payload)
+			assert.NoError(t, err)
+		}
+		return response.Code, payload.Snapshot
+	}
+
+	code, snapshot := readSnapshot("2020-05-20T12:00:00Z")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "2020-05-20T12:00:00Z", snapshot.Timestamp.Format(time.RFC3339))
+
+	code, snapshot = readSnapshot("2020-05-25T00:00:00Z")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "2020-05-20T12:00:00Z", snapshot.Timestamp.Format(time.RFC3339))
+
+	code, snapshot = readSnapshot("2021-01-01T10:00:00+02:00")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "2020-05-27T14:15:35Z", snapshot.Timestamp.Format(time.RFC3339))
+
+	code, _ = readSnapshot("2020-05-13T10:14:59Z")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, _ = readSnapshot("yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+// TestEffectiveConfiguration checks that effective configuration is
+// returned in debug mode only and that sensitive options are redacted
+func TestEffectiveConfiguration(t *testing.T) {
+	config := server.Configuration{
+		Debug:          true,
+		TLSKeyFile:     "/etc/secret/tls.key",
+		DebugUsername:  "admin",
+		DebugPassword:  "secret",
+		RequestTimeout: 90 * time.Second,
+	}
+	serv := newTestServer(t, config)
+
+	request := httptest.NewRequest(http.MethodGet, testAPIPrefix+"debug/config", nil)
+	request.SetBasicAuth("admin", "secret")
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var payload struct {
+		Config map[string]interface{} `json:"config"`
+	}
+	err := json.Unmarshal(response.Body.Bytes(), &payload)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "[redacted]", payload.Config["tls_key_file"])
+	assert.Equal(t, "[redacted]", payload.Config["debug_password"])
+	assert.Equal(t, "", payload.Config["tls_cert_file"])
+	assert.Equal(t, "admin", payload.Config["debug_username"])
+	assert.Equal(t, "1m30s", payload.Config["request_timeout"])
+	assert.Equal(t, testAPIPrefix, payload.Config["api_prefix"])
+	assert.NotContains(t, response.Body.String(), "/etc/secret")
+
+	config.Debug = false
+	serv = newTestServer(t, config)
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"debug/config", nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+// TestReadRuleHitsByCategory checks that rule hits are counted for all
+// categories, including categories without hits
+func TestReadRuleHitsByCategory(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+	serv.Groups = map[string]groups.Group{
+		"security":             {Name: "Security", Tags: []string{"security"}},
+		"service_availability": {Name: "Service Availability", Tags: []string{"service_availability"}},
+		"fault_tolerance":      {Name: "Fault Tolerance", Tags: []string{"fault_tolerance"}},
+	}
+
+	url := testAPIPrefix + "cluster/" + testExistingCluster + "/categories"
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var payload struct {
+		Categories map[string]int `json:"categories"`
+	}
+	err := json.Unmarshal(response.Body.Bytes(), &payload)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		"Security":             1,
+		"Service Availability": 5,
+		"Fault Tolerance":      0,
+	}, payload.Categories)
+
+	url = testAPIPrefix + "cluster/00000000-0000-0000-0000-000000000000/categories"
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+// TestOrgFailureProbability checks that failures are injected only into
+// reports for clusters of selected organizations
+func TestOrgFailureProbability(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{
+		OrgFailureProbability: map[string]float64{"11789772": 1, "2": 0},
+	})
+
+	readReport := func(path string) int {
+		url := testAPIPrefix + "report/" + path
+		return sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil)).Code
+	}
+
+	assert.Equal(t, http.StatusInternalServerError, readReport(testExistingCluster))
+	assert.Equal(t, http.StatusInternalServerError, readReport("11789772/"+testExistingCluster))
+	assert.Equal(t, http.StatusOK, readReport("2/00000002-624a-49a5-bab8-4fdc5e51a266"))
+	assert.Equal(t, http.StatusOK, readReport("00000001-624a-49a5-bab8-4fdc5e51a266"))
+}
+
+// TestDeleteReport checks that deleted report is not found until the state
+// is reset or data are reloaded
+func TestDeleteReport(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{Debug: true})
+
+	readReport := func() int {
+		url := testAPIPrefix + "report/" + testExistingCluster
+		return sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil)).Code
+	}
+	deleteReport := func() int {
+		url := testAPIPrefix + "debug/report/" + testExistingCluster
+		return sendRequest(serv, httptest.NewRequest(http.MethodDelete, url, nil)).Code
+	}
+
+	assert.Equal(t, http.StatusOK, deleteReport())
+	assert.Equal(t, http.StatusNotFound, readReport())
+	assert.Equal(t, http.StatusNotFound, deleteReport())
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"debug/reset", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, http.StatusOK, readReport())
+
+	assert.Equal(t, http.StatusOK, deleteReport())
+	response = sendRequest(serv, httptest.NewRequest(http.MethodPost, testAPIPrefix+"debug/reload", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, http.StatusOK, readReport())
+}
+
+// TestRuleHitFrequency checks that rules are sorted by number of clusters
+// they hit and that the frequencies are recomputed when report is patched
+func TestRuleHitFrequency(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{Debug: true})
+
+	readFrequencies := func(query string) []storage.RuleFrequency {
+		url := testAPIPrefix + "content/frequency" + query
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var payload struct {
+			Rules []storage.RuleFrequency `json:"rules"`
+		}
+		err := json.Unmarshal(response.Body.Bytes(), &payload)
+		assert.NoError(t, err)
+		return payload.Rules
+	}
+
+	frequencies := readFrequencies("")
+	assert.NotEmpty(t, frequencies)
+	for i := 1; i < len(frequencies); i++ {
+		assert.GreaterOrEqual(t, frequencies[i-1].Clusters, frequencies[i].Clusters)
+	}
+	assert.Equal(t, frequencies[:2], readFrequencies("?limit=2"))
+
+	// remove all rule hits from one report
+	url := testAPIPrefix + "debug/report/" + testExistingCluster
+	request := httptest.NewRequest(http.MethodPatch, url, strings.NewReader(`{"reports": {"data": []}}`))
+	request.Header.Set("Content-Type", server.ContentTypeMergePatch)
+	assert.Equal(t, http.StatusOK, sendRequest(serv, request).Code)
+
+	total := func(frequencies []storage.RuleFrequency) int {
+		sum := 0
+		for _, frequency := range frequencies {
+			sum += frequency.Clusters
+		}
+		return sum
+	}
+	assert.Equal(t, total(frequencies)-7, total(readFrequencies("")))
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"content/frequency?limit=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestAccessToOrganizations checks that all known organizations are returned
+// sorted with flag whether they can be accessed
+func TestAccessToOrganizations(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/access", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var payload struct {
+		Organizations []server.OrganizationAccess `json:"organizations"`
+	}
+	err := json.Unmarshal(response.Body.Bytes(), &payload)
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, payload.Organizations)
+	for i, access := range payload.Organizations {
+		if i > 0 {
+			assert.Less(t, payload.Organizations[i-1].OrgID, access.OrgID)
+		}
+		assert.Equal(t, access.OrgID != 11940171, access.Allowed, access.OrgID)
+	}
+	assert.Contains(t, payload.Organizations, server.OrganizationAccess{OrgID: 11940171, Allowed: false})
+	assert.Contains(t, payload.Organizations, server.OrganizationAccess{OrgID: 5, Allowed: true})
+}
+
+// TestJSONContentType checks that Content-Type is set for all JSON responses
+func TestJSONContentType(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+testExistingCluster, nil),
+		httptest.NewRequest(http.MethodGet, testAPIPrefix+"clusters/pattern?glob=00000002-*", nil),
+		httptest.NewRequest(http.MethodGet, testAPIPrefix+"clusters/11789772", nil),
+		httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations", nil),
+	}
+
+	for _, request := range requests {
+		response := sendRequest(serv, request)
+		assert.Equal(t, http.StatusOK, response.Code, request.URL.Path)
+		assert.Equal(t, server.ContentTypeJSON, response.Header().Get("Content-Type"), request.URL.Path)
+	}
+}
+
+// TestRequestTimeout checks that request not processed in time is refused
+func TestRequestTimeout(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{
+		RequestTimeout:      10 * time.Millisecond,
+		LatencyDistribution: "constant",
+		LatencyMean:         time.Second,
+	})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+testExistingCluster, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	assert.Equal(t, server.ContentTypeJSON, response.Header().Get("Content-Type"))
+	assert.Contains(t, response.Body.String(), `"status"`)
+
+	serv = newTestServer(t, server.Configuration{RequestTimeout: time.Minute})
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+testExistingCluster, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+// TestListOfClustersChangedSince checks that only clusters with report
+// changed after given time are returned
+func TestListOfClustersChangedSince(t *testing.T) {
+	const patchedCluster = "00000002-6577-4e80-85e7-697cb646ff37"
+	// far in the future, so report files are older
+	serv := newTestServer(t, server.Configuration{Debug: true, FrozenTime: "2100-01-01T12:00:00Z"})
+
+	request := httptest.NewRequest(http.MethodPatch, testAPIPrefix+"debug/report/"+patchedCluster, strings.NewReader(`{"status": "ok"}`))
+	request.Header.Set("Content-Type", server.ContentTypeMergePatch)
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	url := testAPIPrefix + "organizations/2/clusters?changed_since="
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, url+"2100-01-01T00:00:00Z", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"clusters":["`+patchedCluster+`"]`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, url+"2100-01-02T00:00:00Z", nil))
+	assert.Contains(t, response.Body.String(), `"clusters":[]`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, url+"yesterday", nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestConfiguredResponseHeaders checks that configured headers are added to
+// all responses without overriding headers set by handlers
+func TestConfiguredResponseHeaders(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{
+		ResponseHeaders: map[string]string{
+			"x-rh-edge-reference-id": "mock",
+			"Content-Type":           "text/plain",
+		},
+	})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "mock", response.Header().Get("X-Rh-Edge-Reference-Id"))
+	assert.Equal(t, server.ContentTypeJSON, response.Header().Get("Content-Type"))
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"unknown", nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+	assert.Equal(t, "mock", response.Header().Get("X-Rh-Edge-Reference-Id"))
+}
+
+// TestReadReportForClustersByPattern checks that reports are returned for
+// clusters matching the pattern and that the list is truncated to the limit
+func TestReadReportForClustersByPattern(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{MaxClustersPerRequest: 2})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"clusters/pattern?glob=00000002-*", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), "00000002-624a-49a5-bab8-4fdc5e51a266")
+	assert.Contains(t, response.Body.String(), `"truncated": true`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"clusters/pattern?glob=[", nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestReadReportForClustersAsNDJSON checks that reports are returned as
+// newline delimited JSON when requested
+func TestReadReportForClustersAsNDJSON(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	body := strings.NewReader(`{"clusters": ["` + testExistingCluster + `", "00000000-0000-0000-0000-000000000000"]}`)
+	request := httptest.NewRequest(http.MethodPost, testAPIPrefix+"clusters", body)
+	request.Header.Set("Accept", server.ContentTypeNDJSON)
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, server.ContentTypeNDJSON, response.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(response.Body.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"report":`)
+	assert.Contains(t, lines[1], `"error":`)
+}
+
+// TestSyntheticClusters checks that synthetic clusters are listed for the
+// selected organization and that they are backed by reports
+func TestSyntheticClusters(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{
+		SyntheticClustersOrgID: 11789772,
+		SyntheticClustersCount: 1000,
+	})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/11789772/clusters", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), "00b3e5cc-5555-5555-5555-000000001000")
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/11789772/00b3e5cc-5555-5555-5555-000000000001", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"reports"`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/1/00b3e5cc-5555-5555-5555-000000000001", nil))
+	assert.Equal(t, http.StatusForbidden, response.Code)
+}
+
+// TestDisableRuleForCluster checks that rule disabled for cluster is filtered
+// out from the cluster report until it is enabled again
+func TestDisableRuleForCluster(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+	ruleURL := testAPIPrefix + "clusters/" + testExistingCluster + "/rules/ccx_rules_ocp.external.rules.node_installer_degraded/"
+	summaryURL := testAPIPrefix + "report/" + testExistingCluster + "/summary"
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodPut, ruleURL+"disable", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, summaryURL, nil))
+	assert.Contains(t, response.Body.String(), `"rule_hits": 6`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"clusters/"+testExistingCluster+"/rules/disabled", nil))
+	assert.Contains(t, response.Body.String(), "node_installer_degraded")
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodPut, ruleURL+"enable", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, summaryURL, nil))
+	assert.Contains(t, response.Body.String(), `"rule_hits": 7`)
+}
+
+// TestMethodNotAllowed checks that unsupported method is refused with 405 and
+// that the allowed methods are listed in Allow header
+func TestMethodNotAllowed(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodPut, testAPIPrefix+"report/"+testExistingCluster, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, response.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", response.Header().Get("Allow"))
+	assert.Contains(t, response.Body.String(), `"status":"Method Not Allowed"`)
+}
+
+// TestTLSConfig checks that minimum TLS version and cipher suites are applied
+// to TLS handshake and that improper values are refused
+func TestTLSConfig(t *testing.T) {
+	tlsConfig, err := server.TLSConfig(server.Configuration{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	assert.Empty(t, tlsConfig.CipherSuites)
+
+	_, err = server.TLSConfig(server.Configuration{TLSMinVersion: "1.1"})
+	assert.Error(t, err)
+	_, err = server.TLSConfig(server.Configuration{TLSCipherSuites: []string{"TLS_FOO"}})
+	assert.Error(t, err)
+
+	handshake := func(serverConfig server.Configuration, clientConfig *tls.Config) error {
+		tlsConfig, err := server.TLSConfig(serverConfig)
+		assert.NoError(t, err)
+
+		testServer := httptest.NewUnstartedServer(http.NotFoundHandler())
+		testServer.TLS = tlsConfig
+		testServer.StartTLS()
+		defer testServer.Close()
+
+		// #nosec G402
+		clientConfig.InsecureSkipVerify = true
+		connection, err := tls.Dial("tcp", testServer.Listener.Addr().String(), clientConfig)
+		if err == nil {
+			err = connection.Close()
+		}
+		return err
+	}
+
+	tls12 := &tls.Config{MaxVersion: tls.VersionTLS12}
+	assert.NoError(t, handshake(server.Configuration{}, tls12))
+	assert.Error(t, handshake(server.Configuration{TLSMinVersion: "1.3"}, tls12))
+
+	cipherSuites := server.Configuration{TLSCipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"}}
+	assert.NoError(t, handshake(cipherSuites, &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}))
+	assert.Error(t, handshake(cipherSuites, &tls.Config{
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384},
+	}))
+}
+
+// TestServeTLS checks that self-signed certificate is used when certificate
+// and key files are not configured and that server refuses to start when only
+// one of them is specified
+func TestServeTLS(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "mock.sock")
+	serv := newTestServer(t, server.Configuration{
+		Address: "unix:" + socketPath,
+		TLS:     true,
+	})
+
+	started := make(chan error, 1)
+	go func() {
+		started <- serv.Start()
+	}()
+
+	var connection *tls.Conn
+	assert.Eventually(t, func() bool {
+		var err error
+		connection, err = tls.Dial("unix", socketPath, &tls.Config{
+			ServerName: "localhost",
+			// #nosec G402
+			InsecureSkipVerify: true,
+		})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	if connection != nil {
+		certificates := connection.ConnectionState().PeerCertificates
+		assert.Len(t, certificates, 1)
+		assert.Equal(t, []string{"localhost"}, certificates[0].DNSNames)
+		assert.NoError(t, connection.Close())
+	}
+
+	assert.NoError(t, serv.Stop(context.Background()))
+	assert.NoError(t, <-started)
+
+	for _, config := range []server.Configuration{
+		{TLSCertFile: "/etc/secret/tls.crt"},
+		{TLSKeyFile: "/etc/secret/tls.key"},
+	} {
+		config.Address = "127.0.0.1:0"
+		config.TLS = true
+		err := newTestServer(t, config).Start()
+		assert.EqualError(t, err, "both tls_cert_file and tls_key_file need to be specified")
+	}
+}
+
+// TestReadReportWithClusterInfo checks that cluster info is added to report
+// metadata when enabled and that placeholder values are used for cluster
+// without info file
+func TestReadReportWithClusterInfo(t *testing.T) {
+	type reportMeta struct {
+		Reports struct {
+			Meta map[string]interface{} `json:"meta"`
+		} `json:"reports"`
+	}
+
+	readMeta := func(serv *server.HTTPServer, cluster string) map[string]interface{} {
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+cluster, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var report reportMeta
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &report))
+		return report.Reports.Meta
+	}
+
+	meta := readMeta(newTestServer(t, server.Configuration{}), testExistingCluster)
+	assert.NotContains(t, meta, "cluster_version")
+
+	serv := newTestServer(t, server.Configuration{ClusterInfo: true})
+
+	meta = readMeta(serv, testExistingCluster)
+	assert.Equal(t, "4.5.2", meta["cluster_version"])
+	assert.Equal(t, true, meta["managed"])
+	assert.Equal(t, "2020-05-27T14:15:35Z", meta["last_checked_at"])
+	assert.Equal(t, float64(7), meta["count"])
+
+	meta = readMeta(serv, "34c3ecc5-624a-49a5-bab8-4fdc5e51a267")
+	assert.Equal(t, storage.DefaultClusterVersion, meta["cluster_version"])
+	assert.Equal(t, false, meta["managed"])
+}
+
+// TestReadRuleContent checks that content of rule is returned together with
+// language of the content
+func TestReadRuleContent(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+	url := testAPIPrefix + "content/ccx_rules_ocp.external.rules.node_installer_degraded"
+
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	request.Header.Set("Accept-Language", "de-DE, de;q=0.9, en;q=0.5")
+	response := sendRequest(serv, request)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "en", response.Header().Get("Content-Language"))
+
+	var body struct {
+		Content []types.RuleWithContent `json:"content"`
+	}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Len(t, body.Content, 1)
+	assert.Equal(t, types.ErrorKey("NODE_INSTALLER_DEGRADED"), body.Content[0].ErrorKey)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"content/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	// other content endpoints take precedence
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"content/frequency", nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Empty(t, response.Header().Get("Content-Language"))
+}
+
+// TestStripPrefix checks that prefix added by proxy is removed from request
+// path before routing and that requests without the prefix are routed as is
+func TestStripPrefix(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{StripPrefix: "/gateway/mock/"})
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, "/gateway/mock"+testAPIPrefix+"report/"+testExistingCluster, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"count": 7`)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+testExistingCluster, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, "/gateway/mockery"+testAPIPrefix+"report/"+testExistingCluster, nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+// TestNoContentForEmptyReport checks that report without rule hits is
+// returned with 204 No Content only when enabled
+func TestNoContentForEmptyReport(t *testing.T) {
+	const emptyCluster = "eeeeeeee-eeee-eeee-eeee-000000000001"
+
+	serv := newTestServer(t, server.Configuration{})
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+emptyCluster, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"count": 0`)
+
+	serv = newTestServer(t, server.Configuration{NoContentForEmptyReports: true})
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+emptyCluster, nil))
+	assert.Equal(t, http.StatusNoContent, response.Code)
+	assert.Empty(t, response.Body.String())
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"report/"+testExistingCluster, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+// TestMaxConcurrentRequests checks that requests exceeding the limit of
+// concurrent requests are refused and that health endpoint is not limited
+func TestMaxConcurrentRequests(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{
+		Debug:                 true,
+		MaxConcurrentRequests: 1,
+		LatencyDistribution:   "constant",
+		LatencyMean:           500 * time.Millisecond,
+	})
+	handler := serv.Initialize("")
+	url := testAPIPrefix + "report/" + testExistingCluster
+
+	// the first request is delayed, so it is processed while other requests
+	// are sent
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		done <- recorder
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	refused := httptest.NewRecorder()
+	handler.ServeHTTP(refused, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, refused.Code)
+	assert.Equal(t, "1", refused.Header().Get("Retry-After"))
+
+	health := httptest.NewRecorder()
+	handler.ServeHTTP(health, httptest.NewRequest(http.MethodGet, testAPIPrefix, nil))
+	assert.Equal(t, http.StatusOK, health.Code)
+
+	first := <-done
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "1", first.Header().Get("X-In-Flight-Requests"))
+}
+
+// TestDiffReports checks that rule hits present in report of one cluster
+// only are returned
+func TestDiffReports(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{Debug: true})
+	diffURL := func(a, b string) string {
+		return testAPIPrefix + "debug/diff?a=" + a + "&b=" + b
+	}
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, diffURL(testExistingCluster, "34c3ecc5-624a-49a5-bab8-4fdc5e51a267"), nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	var body struct {
+		Diff storage.ReportDiff `json:"diff"`
+	}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Empty(t, body.Diff.Added)
+	assert.Equal(t, []types.RuleSelector{"ccx_rules_ocp.external.rules.node_installer_degraded|NODE_INSTALLER_DEGRADED"}, body.Diff.Removed)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, diffURL("34c3ecc5-624a-49a5-bab8-4fdc5e51a267", testExistingCluster), nil))
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &body))
+	assert.Equal(t, []types.RuleSelector{"ccx_rules_ocp.external.rules.node_installer_degraded|NODE_INSTALLER_DEGRADED"}, body.Diff.Added)
+	assert.Empty(t, body.Diff.Removed)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, diffURL(testExistingCluster, "00000000-0000-0000-0000-000000000000"), nil))
+	assert.Equal(t, http.StatusNotFound, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, diffURL(testExistingCluster, testExistingCluster), nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	response = sendRequest(serv, httptest.NewRequest(http.MethodGet, diffURL(testExistingCluster, ""), nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// TestStreamReportForCluster checks that report is streamed as server-sent
+// event when it changes, but not when only time of template rendering
+// changes
+func TestStreamReportForCluster(t *testing.T) {
+	const cluster = "dddddddd-0000-0000-0000-000000000001"
+
+	dir := newTestDataDir(t, map[string]string{
+		"report_template_dddddddd.json": `{"reports": {"meta": {"count": 0, "last_checked_at": "{{.Now}}"}, "data": []}, "status": "ok"}`,
+	})
+	serv := newTestServerWithData(t, server.Configuration{
+		FrozenTime:         "2021-01-01T00:00:00Z",
+		StreamPollInterval: 10 * time.Millisecond,
+	}, dir)
+
+	httpServer := httptest.NewServer(serv.Initialize(""))
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+testAPIPrefix+"report/"+cluster+"/stream", nil)
+	assert.NoError(t, err)
+	response, err := http.DefaultClient.Do(request)
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, response.Body.Close())
+	}()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, server.ContentTypeEventStream, response.Header.Get("Content-Type"))
+
+	// data of all received events
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			if data := strings.TrimPrefix(scanner.Text(), "data: "); data != scanner.Text() {
+				events <- data
+			}
+		}
+	}()
+
+	nextEvent := func() string {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("report event has not been received")
+			return ""
+		}
+	}
+
+	assert.Contains(t, nextEvent(), `"last_checked_at":"2021-01-01T00:00:00Z"`)
+
+	// report rendered at different time is not considered changed
+	serv.Clock.(*clock.MockClock).Advance(time.Minute)
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %s", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// deleted cluster has no report to be sent, restored one is sent again
+	serv.Storage.SetClusterDeleted(cluster, true)
+	time.Sleep(100 * time.Millisecond)
+	serv.Storage.SetClusterDeleted(cluster, false)
+	assert.Contains(t, nextEvent(), `"last_checked_at":"2021-01-01T00:01:00Z"`)
+}
+
+// TestSubscribeToReports checks that WebSocket client receives report for
+// subscribed cluster, cluster names are normalized and improper ones and
+// subscriptions above the limit are ignored
+func TestSubscribeToReports(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{StreamPollInterval: 10 * time.Millisecond})
+
+	httpServer := httptest.NewServer(serv.Initialize(""))
+	defer httpServer.Close()
+
+	url := "ws" + strings.TrimPrefix(httpServer.URL, "http") + testAPIPrefix + "reports/ws"
+	connection, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		assert.NoError(t, connection.Close())
+	}()
+
+	// all updates received from the server
+	updates := make(chan server.ReportUpdate)
+	go func() {
+		defer close(updates)
+		for {
+			var update server.ReportUpdate
+			if connection.ReadJSON(&update) != nil {
+				return
+			}
+			updates <- update
+		}
+	}()
+
+	expectUpdate := func(cluster types.ClusterName) {
+		select {
+		case update := <-updates:
+			assert.Equal(t, cluster, update.Cluster)
+			assert.True(t, json.Valid(update.Report))
+		case <-time.After(5 * time.Second):
+			t.Fatal("report update has not been received")
+		}
+	}
+	expectNoUpdate := func() {
+		select {
+		case update := <-updates:
+			t.Fatalf("unexpected update for cluster %s", update.Cluster)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	assert.NoError(t, connection.WriteJSON(server.ReportSubscriptionRequest{
+		Subscribe: []types.ClusterName{"not-a-cluster", types.ClusterName(strings.ToUpper(testExistingCluster))},
+	}))
+	expectUpdate(testExistingCluster)
+
+	assert.NoError(t, connection.WriteJSON(server.ReportSubscriptionRequest{
+		Unsubscribe: []types.ClusterName{types.ClusterName(strings.ToUpper(testExistingCluster))},
+	}))
+
+	// clusters without report fill all subscriptions, so the next one is
+	// refused
+	clusters := make([]types.ClusterName, 100)
+	for i := range clusters {
+		clusters[i] = types.ClusterName(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1))
+	}
+	assert.NoError(t, connection.WriteJSON(server.ReportSubscriptionRequest{Subscribe: clusters}))
+	assert.NoError(t, connection.WriteJSON(server.ReportSubscriptionRequest{
+		Subscribe: []types.ClusterName{testExistingCluster},
+	}))
+	expectNoUpdate()
+
+	assert.NoError(t, connection.WriteJSON(server.ReportSubscriptionRequest{Unsubscribe: clusters[:1]}))
+	assert.NoError(t, connection.WriteJSON(server.ReportSubscriptionRequest{
+		Subscribe: []types.ClusterName{testExistingCluster},
+	}))
+	expectUpdate(testExistingCluster)
+}
+
+// TestGroupsAndRuleContentInYAML checks that list of groups with counts and
+// rule content are returned in YAML when requested via Accept header and in
+// JSON when the header is malformed
+func TestGroupsAndRuleContentInYAML(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+	serv.Groups = map[string]groups.Group{
+		"security":    {Name: "Security", Tags: []string{"security"}},
+		"performance": {Name: "Performance", Tags: []string{"performance"}},
+	}
+
+	for _, url := range []string{
+		testAPIPrefix + "groups?withCounts=true",
+		testAPIPrefix + "content/ccx_rules_ocp.external.rules.node_installer_degraded",
+	} {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		request.Header.Set("Accept", "application/yaml")
+		response := sendRequest(serv, request)
+		assert.Equal(t, http.StatusOK, response.Code, url)
+		assert.Equal(t, server.ContentTypeYAML, response.Header().Get("Content-Type"), url)
+
+		var payload map[string]interface{}
+		assert.NoError(t, yaml.Unmarshal(response.Body.Bytes(), &payload), url)
+		assert.Equal(t, "ok", payload["status"], url)
+
+		request = httptest.NewRequest(http.MethodGet, url, nil)
+		request.Header.Set("Accept", "application/yaml; =broken")
+		response = sendRequest(serv, request)
+		assert.Equal(t, http.StatusOK, response.Code, url)
+		assert.Equal(t, server.ContentTypeJSON, response.Header().Get("Content-Type"), url)
+		assert.True(t, json.Valid(response.Body.Bytes()), url)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, testAPIPrefix+"groups?withCounts=true", nil)
+	request.Header.Set("Accept", "text/yaml")
+	response := sendRequest(serv, request)
+
+	var payload struct {
+		Groups []struct {
+			Title     string `yaml:"title"`
+			RuleCount int    `yaml:"rule_count"`
+		} `yaml:"groups"`
+	}
+	assert.NoError(t, yaml.Unmarshal(response.Body.Bytes(), &payload))
+	assert.Len(t, payload.Groups, 2)
+	assert.Equal(t, "Performance", payload.Groups[0].Title)
+}
+
+// TestReportTemplateRenderingInvalidJSON checks that report template
+// rendering into invalid JSON is reported as 500 Internal Server Error
+func TestReportTemplateRenderingInvalidJSON(t *testing.T) {
+	dir := newTestDataDir(t, map[string]string{
+		"report_template_ffffffff.json": `{"cluster": {{.ClusterName}}}`,
+	})
+	serv := newTestServerWithData(t, server.Configuration{}, dir)
+
+	url := testAPIPrefix + "report/ffffffff-0000-0000-0000-000000000001"
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+	assert.Contains(t, response.Body.String(), "rendered invalid JSON")
+}
+
+// TestChaosMode checks that seeded chaos mode injects reproducible sequence of
+// failures, that health endpoint is never affected and that latency spike is
+// interrupted when request is cancelled
+func TestChaosMode(t *testing.T) {
+	config := server.Configuration{
+		ChaosProbability: 0.5,
+		ChaosSeed:        42,
+		ChaosMaxLatency:  time.Millisecond,
+	}
+
+	statusCodes := func(handler http.Handler, path string) []int {
+		codes := make([]int, 10)
+		for i := range codes {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testAPIPrefix+path, nil))
+			codes[i] = recorder.Code
+		}
+		return codes
+	}
+
+	const ok, failure = http.StatusOK, http.StatusInternalServerError
+	expected := []int{failure, ok, failure, ok, ok, failure, ok, ok, ok, ok}
+	assert.Equal(t, expected, statusCodes(newTestServer(t, config).Initialize(""), "organizations"))
+	// the same sequence is injected after restart
+	assert.Equal(t, expected, statusCodes(newTestServer(t, config).Initialize(""), "organizations"))
+
+	config.ChaosProbability = 1
+	for _, code := range statusCodes(newTestServer(t, config).Initialize(""), "") {
+		assert.Equal(t, ok, code)
+	}
+
+	// cancelled requests are either failed or not processed at all
+	config.ChaosMaxLatency = time.Hour
+	handler := newTestServer(t, config).Initialize("")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 10; i++ {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations", nil).WithContext(ctx)
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != failure {
+			assert.Empty(t, recorder.Body.String())
+		}
+	}
+}
+
+// TestLatencyDistributions checks that delays are sampled from seeded latency
+// distributions and reported in X-Mock-Latency header in debug mode
+func TestLatencyDistributions(t *testing.T) {
+	latencies := func(config server.Configuration, path string) []string {
+		handler := newTestServer(t, config).Initialize("")
+		values := make([]string, 3)
+		for i := range values {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testAPIPrefix+path, nil))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			values[i] = recorder.Header().Get("X-Mock-Latency")
+		}
+		return values
+	}
+
+	testCases := []struct {
+		config   server.Configuration
+		expected []string
+	}{
+		{
+			server.Configuration{LatencyDistribution: "constant", LatencyMean: time.Millisecond},
+			[]string{"1ms", "1ms", "1ms"},
+		},
+		{
+			server.Configuration{LatencyDistribution: "uniform", LatencyMin: time.Millisecond, LatencyMax: 2 * time.Millisecond},
+			[]string{"1.278675ms", "1.856411ms", "1.87876ms"},
+		},
+		{
+			server.Configuration{LatencyDistribution: "normal", LatencyMean: time.Millisecond, LatencyStdDev: time.Millisecond},
+			[]string{"2.55363ms", "1.125256ms", "505.625µs"},
+		},
+		{
+			server.Configuration{LatencyDistribution: "exponential", LatencyMean: time.Millisecond},
+			[]string{"495.738µs", "130.547µs", "153.233µs"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.config.LatencyDistribution, func(t *testing.T) {
+			config := testCase.config
+			config.LatencySeed = 42
+			config.Debug = true
+			assert.Equal(t, testCase.expected, latencies(config, "organizations"))
+
+			// health endpoint is not delayed
+			assert.Equal(t, []string{"", "", ""}, latencies(config, ""))
+
+			// delay is not reported outside of debug mode
+			config.Debug = false
+			assert.Equal(t, []string{"", "", ""}, latencies(config, "organizations"))
+		})
+	}
+}
+
+// TestListOfClustersForOrganizationAsCSV checks that list of clusters is
+// exported as CSV attachment when requested by Accept header or by format
+// query parameter
+func TestListOfClustersForOrganizationAsCSV(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{})
+	url := testAPIPrefix + "organizations/2/clusters"
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	var payload struct {
+		Clusters []string `json:"clusters"`
+	}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &payload))
+	assert.Len(t, payload.Clusters, 3)
+
+	expected := [][]string{{"cluster"}}
+	for _, cluster := range payload.Clusters {
+		expected = append(expected, []string{cluster})
+	}
+
+	byHeader := httptest.NewRequest(http.MethodGet, url, nil)
+	byHeader.Header.Set("Accept", "text/csv")
+	byParam := httptest.NewRequest(http.MethodGet, url+"?format=csv", nil)
+
+	for _, request := range []*http.Request{byHeader, byParam} {
+		response := sendRequest(serv, request)
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, server.ContentTypeCSV, response.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="clusters_2.csv"`, response.Header().Get("Content-Disposition"))
+
+		rows, err := csv.NewReader(response.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, rows)
+	}
+}
+
+// TestListOfClustersForOrganizationSortedByHits checks that clusters are
+// sorted by number of rule hits and clusters without report are sorted as
+// clusters with zero hits
+func TestListOfClustersForOrganizationSortedByHits(t *testing.T) {
+	const (
+		noReport = "ffffffff-0000-0000-0000-000000000001"
+		oneHit   = "00000003-8933-4a3a-8634-3328fe806e08"
+		manyHits = "34c3ecc5-624a-49a5-bab8-4fdc5e51a266"
+	)
+
+	dir := newTestDataDir(t, map[string]string{
+		"organizations.json": `{"7": ["` + oneHit + `", "` + noReport + `", "` + manyHits + `"]}`,
+	})
+	serv := newTestServerWithData(t, server.Configuration{}, dir)
+
+	clusters := func(query string) []string {
+		url := testAPIPrefix + "organizations/7/clusters?" + query
+		response := sendRequest(serv, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusOK, response.Code)
+
+		var payload struct {
+			Clusters []string `json:"clusters"`
+		}
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &payload))
+		return payload.Clusters
+	}
+
+	descending := []string{manyHits, oneHit, noReport}
+	assert.Equal(t, descending, clusters("sort=hits"))
+	assert.Equal(t, descending, clusters("sort=hits&order=desc"))
+	assert.Equal(t, []string{noReport, oneHit, manyHits}, clusters("sort=hits&order=asc"))
+
+	response := sendRequest(serv, httptest.NewRequest(http.MethodGet, testAPIPrefix+"organizations/7/clusters?sort=name", nil))
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+// logBuffer collects log messages written concurrently by the server
+type logBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (buffer *logBuffer) Write(p []byte) (int, error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return buffer.buffer.Write(p)
+}
+
+func (buffer *logBuffer) String() string {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return buffer.buffer.String()
+}
+
+// startTestServer starts server listening on Unix domain socket and returns
+// client connected to the socket. Server is accepting requests (and handling
+// signals) when the function returns, error returned by Start is sent into
+// the channel.
+func startTestServer(t *testing.T, config server.Configuration) (*server.HTTPServer, *http.Client, <-chan error) {
+	socketPath := filepath.Join(t.TempDir(), "mock.sock")
+	config.Address = "unix:" + socketPath
+	serv := newTestServer(t, config)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- serv.Start()
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	assert.Eventually(t, func() bool {
+		response, err := client.Get("http://localhost" + testAPIPrefix)
+		if err != nil {
+			return false
+		}
+		return response.Body.Close() == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return serv, client, started
+}
+
+// TestReloadOnSignal checks that data files are reloaded when the service
+// receives SIGHUP and that summary of reloaded files is logged
+func TestReloadOnSignal(t *testing.T) {
+	logs := &logBuffer{}
+	originalLogger := log.Logger
+	log.Logger = zerolog.New(logs)
+	defer func() {
+		log.Logger = originalLogger
+	}()
+
+	serv, _, started := startTestServer(t, server.Configuration{})
+	expected := serv.Storage.Reload()
+
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+	summary := fmt.Sprintf(`{"level":"info","reports":%d,"report templates":%d,"failures":%d,"message":"Data files reloaded"}`,
+		expected.ReportsCount, expected.ReportTemplatesCount, len(expected.Failures))
+	assert.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), summary)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, serv.Stop(context.Background()))
+	assert.NoError(t, <-started)
+}
+
+// TestExitEndpoint checks that exit code is validated, acknowledged in
+// response and that server is stopped with requested exit code
+func TestExitEndpoint(t *testing.T) {
+	serv := newTestServer(t, server.Configuration{Debug: true})
+	for _, code := range []string{"-1", "256", "foo"} {
+		url := testAPIPrefix + "exit?code=" + code
+		response := sendRequest(serv, httptest.NewRequest(http.MethodPut, url, nil))
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		assert.Contains(t, response.Body.String(), "exit code needs to be an integer in range 0..255")
+	}
+
+	serv, client, started := startTestServer(t, server.Configuration{Debug: true})
+	request, err := http.NewRequest(http.MethodPut, "http://localhost"+testAPIPrefix+"exit?code=42", nil)
+	assert.NoError(t, err)
+	response, err := client.Do(request)
+	assert.NoError(t, err)
+	body, err := ioutil.ReadAll(response.Body)
+	assert.NoError(t, err)
+	assert.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"status": "ok", "exit_code": 42}`, string(body))
+
+	assert.NoError(t, <-started)
+	assert.Equal(t, 42, serv.ExitCode)
+}
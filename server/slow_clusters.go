@@ -0,0 +1,109 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RedHatInsights/insights-operator-utils/responses"
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// defaultSlowClusterDelay is used when SlowClusterDelay is not configured
+const defaultSlowClusterDelay = 30 * time.Second
+
+const retryAfterHeader = "Retry-After"
+
+// slowClusterDelay returns time needed to prepare report for "slow cluster"
+func (server *HTTPServer) slowClusterDelay() time.Duration {
+	if server.Config.SlowClusterDelay > 0 {
+		return server.Config.SlowClusterDelay
+	}
+	return defaultSlowClusterDelay
+}
+
+// handleSlowCluster responds with 202 Accepted and retry hint when report
+// for "slow cluster" is not ready yet. Returns true when the response has
+// been sent already.
+func (server *HTTPServer) handleSlowCluster(writer http.ResponseWriter, clusterName types.ClusterName) bool {
+	if !storage.IsSlowCluster(clusterName) {
+		return false
+	}
+
+	remaining := server.Storage.ReportReadyIn(clusterName, server.slowClusterDelay())
+	if remaining == 0 {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(remaining.Seconds()))
+	log.Info().Str("Cluster name", string(clusterName)).Int("retry after", retryAfter).Msg("Report is not ready yet")
+
+	response := responses.BuildResponse("report is not ready yet")
+	response["retry_after"] = retryAfter
+	writer.Header().Set(retryAfterHeader, strconv.Itoa(retryAfter))
+
+	err := responses.SendAccepted(writer, response)
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+	return true
+}
+
+// separateProcessingClusters moves clusters whose reports are still being
+// processed from the list of reports into the list of processing clusters.
+// Nothing is moved when BatchProcessingDelay is not configured.
+func (server *HTTPServer) separateProcessingClusters(generatedReports *ClusterReports) {
+	delay := server.Config.BatchProcessingDelay
+	if delay <= 0 {
+		return
+	}
+
+	ready := make([]types.ClusterName, 0, len(generatedReports.ClusterList))
+	for _, clusterName := range generatedReports.ClusterList {
+		if server.Storage.ReportReadyIn(clusterName, delay) > 0 {
+			log.Info().Str("Cluster name", string(clusterName)).Msg("Report is still processing")
+			generatedReports.Processing = append(generatedReports.Processing, clusterName)
+			delete(generatedReports.Reports, clusterName)
+			continue
+		}
+		ready = append(ready, clusterName)
+	}
+	generatedReports.ClusterList = ready
+}
+
+// resetState resets state of "slow clusters" and clusters in batch requests
+// so their reports are not ready again, restores the default sets of
+// denied organizations and deleted clusters and restores deleted reports
+// (debug only)
+func (server *HTTPServer) resetState(writer http.ResponseWriter, request *http.Request) {
+	server.Storage.ResetSlowClusters()
+	server.Storage.ResetDeniedOrganizations()
+	server.Storage.ResetDeletedClusters()
+	server.Storage.RestoreDeletedReports()
+	log.Info().Msg("Mock state has been reset")
+
+	err := responses.SendOK(writer, responses.BuildOkResponse())
+	if err != nil {
+		log.Error().Err(err).Msg(responseDataError)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// defaultStreamPollInterval is used when StreamPollInterval is not configured
+const defaultStreamPollInterval = time.Second
+
+// ContentTypeEventStream represents MIME type for server-sent events
+const ContentTypeEventStream = "text/event-stream"
+
+// reportChangeHandler is called by watchReports for each changed report
+type reportChangeHandler func(clusterName types.ClusterName, report types.ClusterReport) error
+
+// streamPollInterval returns how often reports are checked for changes
+func (server *HTTPServer) streamPollInterval() time.Duration {
+	if server.Config.StreamPollInterval > 0 {
+		return server.Config.StreamPollInterval
+	}
+	return defaultStreamPollInterval
+}
+
+// watchReports periodically reads reports for given clusters and calls the
+// onChange handler for each report that differs from the previously seen
+// one. All reports are considered changed in the first round. Changes made
+// by any means (report rotation for changing clusters, data modifications)
+// are detected, reports are compared before report templates are rendered,
+// so time of rendering is not considered a change. The function returns when
+// the context is cancelled or when the handler returns an error.
+func (server *HTTPServer) watchReports(
+	ctx context.Context, clusters []types.ClusterName, onChange reportChangeHandler,
+) error {
+	lastReports := make(map[types.ClusterName]types.ClusterReport)
+
+	ticker := time.NewTicker(server.streamPollInterval())
+	defer ticker.Stop()
+
+	for {
+		for _, clusterName := range clusters {
+			stableReport, err := server.Storage.ReadStableReportForCluster(clusterName)
+			if err != nil {
+				log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+				continue
+			}
+
+			lastReport, seen := lastReports[clusterName]
+			if seen && lastReport == stableReport {
+				continue
+			}
+			lastReports[clusterName] = stableReport
+
+			report, err := server.Storage.ReadReportForCluster(clusterName)
+			if err != nil {
+				log.Error().Err(err).Msg(unableToReadReportErrorMessage)
+				continue
+			}
+
+			err = onChange(clusterName, report)
+			if err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// streamReportForCluster streams report for selected cluster as server-sent
+// events. New event is emitted each time the report changes.
+func (server *HTTPServer) streamReportForCluster(writer http.ResponseWriter, request *http.Request) {
+	clusterName, err := readClusterName(writer, request)
+	if err != nil {
+		// everything has been handled already
+		return
+	}
+
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		log.Error().Msg("Streaming is not supported by response writer")
+		writeError(writer, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	writer.Header().Set(contentTypeHeader, ContentTypeEventStream)
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	log.Info().Str("cluster", string(clusterName)).Msg("Report streaming started")
+
+	// request context is cancelled when client disconnects
+	err = server.watchReports(request.Context(), []types.ClusterName{clusterName},
+		func(clusterName types.ClusterName, report types.ClusterReport) error {
+			// nothing to send until the report is available
+			if report == "" {
+				return nil
+			}
+			err := writeReportEvent(writer, report)
+			if err != nil {
+				return err
+			}
+			flusher.Flush()
+			return nil
+		})
+
+	log.Info().Err(err).Str("cluster", string(clusterName)).Msg("Report streaming finished")
+}
+
+// writeReportEvent writes report as one server-sent event. Report is
+// compacted as event data can't contain newlines.
+func writeReportEvent(writer http.ResponseWriter, report types.ClusterReport) error {
+	var compacted bytes.Buffer
+	err := json.Compact(&compacted, []byte(report))
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(writer, "event: report\ndata: %s\n\n", compacted.Bytes())
+	return err
+}
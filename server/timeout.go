@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// timeoutMessage is used as the error message when request is not processed
+// in time
+const timeoutMessage = "Request has not been processed in time"
+
+// jsonTimeoutWriter sets JSON Content-Type for 503 responses that don't have
+// Content-Type set, i.e. for responses written by http.TimeoutHandler
+type jsonTimeoutWriter struct {
+	http.ResponseWriter
+}
+
+// WriteHeader sets Content-Type when needed and writes the status code
+func (writer jsonTimeoutWriter) WriteHeader(code int) {
+	if code == http.StatusServiceUnavailable && writer.Header().Get(contentTypeHeader) == "" {
+		writer.Header().Set(contentTypeHeader, ContentTypeJSON)
+	}
+	writer.ResponseWriter.WriteHeader(code)
+}
+
+// isStreamingRequest checks if the response to request is streamed, so
+// the request can't be bound by timeout
+func (server *HTTPServer) isStreamingRequest(request *http.Request) bool {
+	if acceptsNDJSON(request) {
+		return true
+	}
+
+	route := mux.CurrentRoute(request)
+	if route == nil {
+		return false
+	}
+
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+
+	apiPrefix := server.apiPrefix()
+	return template == apiPrefix+ReportStreamEndpoint || template == apiPrefix+ReportsWebSocketEndpoint
+}
+
+// newTimeoutMiddleware constructs middleware that refuses requests that are
+// not processed in time specified by RequestTimeout with 503 Service
+// Unavailable. Streaming requests are not bound by the timeout.
+func (server *HTTPServer) newTimeoutMiddleware() mux.MiddlewareFunc {
+	timeout := server.Config.RequestTimeout
+	log.Info().Dur("timeout", timeout).Msg("Request timeout is enabled")
+
+	body, err := json.Marshal(ErrorResponse{Status: timeoutMessage})
+	if err != nil {
+		// should not happen for constant message
+		log.Error().Err(err).Msg(responseDataError)
+	}
+
+	return func(nextHandler http.Handler) http.Handler {
+		timeoutHandler := http.TimeoutHandler(nextHandler, timeout, string(body))
+
+		return http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if server.isStreamingRequest(r) {
+					nextHandler.ServeHTTP(w, r)
+					return
+				}
+				timeoutHandler.ServeHTTP(jsonTimeoutWriter{w}, r)
+			})
+	}
+}
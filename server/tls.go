@@ -0,0 +1,176 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// validity of self-signed certificate generated on server startup
+const selfSignedCertificateValidity = 365 * 24 * time.Hour
+
+// TLS versions that can be specified by TLSMinVersion option
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig returns TLS configuration according to TLSMinVersion and
+// TLSCipherSuites options. TLS 1.2 is the minimum version and secure cipher
+// suites selected by Go are used when the options are not set. Error is
+// returned for unknown version or cipher suite.
+func TLSConfig(config Configuration) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.TLSMinVersion != "" {
+		version, found := tlsVersions[config.TLSMinVersion]
+		if !found {
+			return nil, fmt.Errorf("unsupported minimum TLS version %q, use 1.2 or 1.3", config.TLSMinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	if len(config.TLSCipherSuites) == 0 {
+		return tlsConfig, nil
+	}
+
+	suites := make(map[string]*tls.CipherSuite)
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		suites[suite.Name] = suite
+	}
+
+	for _, name := range config.TLSCipherSuites {
+		suite, found := suites[strings.TrimSpace(name)]
+		if !found {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		if suite.Insecure {
+			log.Warn().Str("cipher suite", suite.Name).Msg("Insecure cipher suite is allowed")
+		}
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, suite.ID)
+	}
+
+	// cipher suites are not configurable in TLS 1.3
+	if tlsConfig.MinVersion == tls.VersionTLS13 {
+		log.Warn().Msg("Cipher suites are ignored when minimum TLS version is 1.3")
+	}
+
+	return tlsConfig, nil
+}
+
+// errIncompleteTLSCertificate is returned when only one of certificate and
+// key files is specified in configuration
+var errIncompleteTLSCertificate = errors.New("both tls_cert_file and tls_key_file need to be specified")
+
+// serveTLS starts HTTPS server on given listener. Certificate and key files specified
+// in configuration are used when available, otherwise self-signed certificate
+// is generated. Error is returned when only one of the files is specified.
+func (server *HTTPServer) serveTLS(listener net.Listener) error {
+	certFile := server.Config.TLSCertFile
+	keyFile := server.Config.TLSKeyFile
+
+	tlsConfig, err := TLSConfig(server.Config)
+	if err == nil && (certFile == "") != (keyFile == "") {
+		err = errIncompleteTLSCertificate
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Improper TLS configuration")
+		// Serve* functions close the listener on error as well
+		_ = listener.Close()
+		return err
+	}
+	server.Serv.TLSConfig = tlsConfig
+
+	if certFile != "" {
+		log.Info().
+			Str("certificate", certFile).
+			Str("key", keyFile).
+			Msg("Using TLS certificate")
+		return server.Serv.ServeTLS(listener, certFile, keyFile)
+	}
+
+	log.Info().Msg("TLS certificate is not specified, generating self-signed one")
+	certificate, err := generateSelfSignedCertificate()
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to generate self-signed certificate")
+		// Serve* functions close the listener on error as well
+		_ = listener.Close()
+		return err
+	}
+
+	tlsConfig.Certificates = []tls.Certificate{certificate}
+
+	// certificate is already part of TLS configuration
+	return server.Serv.ServeTLS(listener, "", "")
+}
+
+// generateSelfSignedCertificate generates new private key and self-signed
+// certificate for localhost
+func generateSelfSignedCertificate() (tls.Certificate, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"Insights Results Aggregator Mock"},
+		},
+		NotBefore:             now,
+		NotAfter:              now.Add(selfSignedCertificateValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback},
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{certificate},
+		PrivateKey:  privateKey,
+	}, nil
+}
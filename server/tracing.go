@@ -0,0 +1,93 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+// OpenTelemetry tracing: W3C trace context is taken from incoming
+// traceparent header and one server span per request is exported to OTLP
+// collector using OTLP/HTTP protocol.
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	// DefaultTracingEndpoint is OTLP/HTTP traces endpoint used when
+	// TracingEndpoint is not configured
+	DefaultTracingEndpoint = "http://localhost:4318/v1/traces"
+
+	// tracingServiceName is service name reported in exported spans
+	tracingServiceName = "insights-results-aggregator-mock"
+)
+
+// newTracerProvider constructs tracer provider exporting spans to OTLP
+// collector specified in configuration. Spans are exported in batches in
+// background until the provider is shut down.
+func newTracerProvider(config Configuration) (*sdktrace.TracerProvider, error) {
+	endpoint := config.TracingEndpoint
+	if endpoint == "" {
+		endpoint = DefaultTracingEndpoint
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("endpoint", endpoint).Msg("Tracing is enabled")
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", tracingServiceName),
+		)),
+	), nil
+}
+
+// routeName returns path template of matched route, or the request path
+// when no route matches
+func routeName(request *http.Request) string {
+	if route := mux.CurrentRoute(request); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return request.URL.Path
+}
+
+// newTracingMiddleware constructs middleware that creates span for each
+// request. Trace context is propagated from traceparent header, requests not
+// sampled by the caller are not exported.
+func (server *HTTPServer) newTracingMiddleware() mux.MiddlewareFunc {
+	return func(nextHandler http.Handler) http.Handler {
+		return otelhttp.NewHandler(nextHandler, tracingServiceName,
+			otelhttp.WithTracerProvider(server.tracerProvider),
+			otelhttp.WithPropagators(propagation.TraceContext{}),
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return r.Method + " " + routeName(r)
+			}),
+		)
+	}
+}
@@ -0,0 +1,237 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// maxSubscriptionsPerConnection is maximum number of clusters one WebSocket
+// client can be subscribed to, further subscriptions are refused
+const maxSubscriptionsPerConnection = 100
+
+// websocketUpgrader upgrades HTTP connections to WebSocket protocol
+var websocketUpgrader = websocket.Upgrader{
+	// this is mock service that needs to be accessible from UI running
+	// on any origin
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// ReportSubscriptionRequest is a message sent by client via WebSocket to
+// change the set of clusters it is subscribed to
+type ReportSubscriptionRequest struct {
+	Subscribe   []types.ClusterName `json:"subscribe"`
+	Unsubscribe []types.ClusterName `json:"unsubscribe"`
+}
+
+// ReportUpdate is a message sent to client via WebSocket each time a report
+// for subscribed cluster changes
+type ReportUpdate struct {
+	Cluster types.ClusterName `json:"cluster"`
+	Report  json.RawMessage   `json:"report"`
+}
+
+// reportSubscriber manages subscriptions for one WebSocket connection.
+// Updates not yet sent to the client are coalesced, so slow clients receive
+// only the latest report for each cluster.
+type reportSubscriber struct {
+	server        *HTTPServer
+	mutex         sync.Mutex
+	subscriptions map[types.ClusterName]context.CancelFunc
+	pending       map[types.ClusterName]types.ClusterReport
+	notify        chan struct{}
+}
+
+// newReportSubscriber constructs subscriber without any subscription
+func newReportSubscriber(server *HTTPServer) *reportSubscriber {
+	return &reportSubscriber{
+		server:        server,
+		subscriptions: make(map[types.ClusterName]context.CancelFunc),
+		pending:       make(map[types.ClusterName]types.ClusterReport),
+		notify:        make(chan struct{}, 1),
+	}
+}
+
+// subscribe starts watching the report for given cluster, subscription is
+// refused when the subscriber is subscribed to too many clusters already
+func (subscriber *reportSubscriber) subscribe(ctx context.Context, clusterName types.ClusterName) {
+	subscriber.mutex.Lock()
+	defer subscriber.mutex.Unlock()
+
+	if _, found := subscriber.subscriptions[clusterName]; found {
+		return
+	}
+	if len(subscriber.subscriptions) >= maxSubscriptionsPerConnection {
+		log.Error().Str("cluster", string(clusterName)).Msg("Too many subscriptions, subscription refused")
+		return
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	subscriber.subscriptions[clusterName] = cancel
+
+	go func() {
+		err := subscriber.server.watchReports(watchCtx, []types.ClusterName{clusterName},
+			func(clusterName types.ClusterName, report types.ClusterReport) error {
+				return subscriber.enqueue(watchCtx, clusterName, report)
+			})
+		log.Debug().Err(err).Str("cluster", string(clusterName)).Msg("Report watching finished")
+	}()
+}
+
+// unsubscribe stops watching the report for given cluster
+func (subscriber *reportSubscriber) unsubscribe(clusterName types.ClusterName) {
+	subscriber.mutex.Lock()
+	defer subscriber.mutex.Unlock()
+
+	if cancel, found := subscriber.subscriptions[clusterName]; found {
+		cancel()
+		delete(subscriber.subscriptions, clusterName)
+		delete(subscriber.pending, clusterName)
+	}
+}
+
+// enqueue stores changed report to be sent to client, older report for the
+// same cluster that has not been sent yet is replaced. Report is dropped when
+// the subscription (represented by its context) has been cancelled already.
+func (subscriber *reportSubscriber) enqueue(ctx context.Context, clusterName types.ClusterName, report types.ClusterReport) error {
+	// nothing to send until the report is available
+	if report == "" {
+		return nil
+	}
+
+	subscriber.mutex.Lock()
+	// subscription is cancelled under the same lock by unsubscribe
+	if ctx.Err() != nil {
+		subscriber.mutex.Unlock()
+		return ctx.Err()
+	}
+	subscriber.pending[clusterName] = report
+	subscriber.mutex.Unlock()
+
+	// don't block when notification is already waiting
+	select {
+	case subscriber.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// takePending returns all pending updates and clears them
+func (subscriber *reportSubscriber) takePending() map[types.ClusterName]types.ClusterReport {
+	subscriber.mutex.Lock()
+	defer subscriber.mutex.Unlock()
+
+	pending := subscriber.pending
+	subscriber.pending = make(map[types.ClusterName]types.ClusterReport)
+	return pending
+}
+
+// sendUpdates writes pending updates to the connection until the context is
+// cancelled or until write fails
+func (subscriber *reportSubscriber) sendUpdates(ctx context.Context, connection *websocket.Conn) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-subscriber.notify:
+		}
+
+		for clusterName, report := range subscriber.takePending() {
+			update := ReportUpdate{
+				Cluster: clusterName,
+				Report:  json.RawMessage(report),
+			}
+			err := connection.WriteJSON(update)
+			if err != nil {
+				log.Error().Err(err).Msg("Unable to send report update")
+				// reading loop will be finished as well
+				_ = connection.Close()
+				return
+			}
+		}
+	}
+}
+
+// subscribeToReports handles WebSocket connection in which client subscribes
+// to reports for several clusters and receives a message whenever any of
+// these reports changes
+func (server *HTTPServer) subscribeToReports(writer http.ResponseWriter, request *http.Request) {
+	connection, err := websocketUpgrader.Upgrade(writer, request, nil)
+	if err != nil {
+		// error response has been sent by upgrader already
+		log.Error().Err(err).Msg("Unable to upgrade connection to WebSocket")
+		return
+	}
+	defer func() {
+		_ = connection.Close()
+	}()
+
+	// stops all watchers and sending loop when the client disconnects
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subscriber := newReportSubscriber(server)
+	go subscriber.sendUpdates(ctx, connection)
+
+	for {
+		var subscription ReportSubscriptionRequest
+		err := connection.ReadJSON(&subscription)
+		switch err.(type) {
+		case nil:
+		case *json.SyntaxError, *json.UnmarshalTypeError:
+			// connection is still usable
+			log.Error().Err(err).Msg("Malformed subscription request")
+			continue
+		default:
+			log.Info().Err(err).Msg("WebSocket connection closed")
+			return
+		}
+
+		for _, clusterName := range subscription.Subscribe {
+			if validClusterName, valid := validSubscriptionClusterName(clusterName); valid {
+				subscriber.subscribe(ctx, validClusterName)
+			}
+		}
+		for _, clusterName := range subscription.Unsubscribe {
+			if validClusterName, valid := validSubscriptionClusterName(clusterName); valid {
+				subscriber.unsubscribe(validClusterName)
+			}
+		}
+	}
+}
+
+// validSubscriptionClusterName validates and normalizes cluster name from
+// subscription request, improper names are logged and ignored
+func validSubscriptionClusterName(clusterName types.ClusterName) (types.ClusterName, bool) {
+	validClusterName, err := storage.ValidateClusterName(string(clusterName))
+	if err != nil {
+		log.Error().Err(err).Msg("Improper cluster name in subscription request")
+		return "", false
+	}
+	return validClusterName, true
+}
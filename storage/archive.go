@@ -0,0 +1,163 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Mock data can be stored in tar.gz archive instead of directory. All
+// entries with name report_*.json (regardless of directory inside the
+// archive) are read as reports or report templates.
+const (
+	archiveSuffix           = ".tar.gz"
+	reportFilePrefix        = "report_"
+	reportFileSuffix        = ".json"
+	archiveReportEntryMatch = reportFilePrefix + "*" + reportFileSuffix
+)
+
+// isArchive checks if mock data path points to tar.gz archive
+func isArchive(path string) bool {
+	return strings.HasSuffix(path, archiveSuffix)
+}
+
+// readArchive reads all report and report template files from given tar.gz
+// archive. Key of returned map is file name without directory.
+func readArchive(path string) (map[string][]byte, error) {
+	// disable "G304 (CWE-22): Potential file inclusion via variable"
+	// #nosec G304
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err := file.Close()
+		if err != nil {
+			log.Error().Err(err).Str("path", path).Msg("Unable to close archive")
+		}
+	}()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string][]byte)
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := filepath.Base(header.Name)
+		if matched, _ := filepath.Match(archiveReportEntryMatch, name); !matched {
+			continue
+		}
+
+		// disable "G110 (CWE-409): Potential DoS vulnerability via decompression bomb"
+		// #nosec G110
+		content, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		entries[name] = content
+	}
+
+	return entries, nil
+}
+
+// readReportsAndTemplatesFromArchive reads all reports and report templates
+// stored in given tar.gz archive, the archive is read only once. Nil map of
+// reports is returned when the archive itself can't be read.
+func readReportsAndTemplatesFromArchive(path string) (map[string]string, []ReloadFailure, map[string]*template.Template, error) {
+	entries, err := readArchive(path)
+	if err != nil {
+		return nil, []ReloadFailure{newReloadFailure(path, "", err)}, nil, err
+	}
+
+	loaded, failures := reportsFromArchiveEntries(path, entries)
+	templates, err := reportTemplatesFromArchiveEntries(entries)
+	return loaded, failures, templates, err
+}
+
+// reportsFromArchiveEntries returns all reports found in entries read from
+// given tar.gz archive
+func reportsFromArchiveEntries(path string, entries map[string][]byte) (map[string]string, []ReloadFailure) {
+	loaded := make(map[string]string)
+	failures := []ReloadFailure{}
+
+	for name, content := range entries {
+		if strings.HasPrefix(name, reportTemplateFilePrefix) || isReportHistoryFile(name) {
+			continue
+		}
+
+		cluster := strings.TrimSuffix(strings.TrimPrefix(name, reportFilePrefix), reportFileSuffix)
+		if !json.Valid(content) {
+			err := fmt.Errorf("report for cluster %s is not valid JSON", cluster)
+			failures = append(failures, newReloadFailure(path+":"+name, cluster, err))
+			continue
+		}
+		loaded[cluster] = string(content)
+	}
+
+	return loaded, failures
+}
+
+// reportTemplatesFromArchiveEntries parses all report templates found in
+// entries read from tar.gz archive
+func reportTemplatesFromArchiveEntries(entries map[string][]byte) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+
+	for name, content := range entries {
+		if !strings.HasPrefix(name, reportTemplateFilePrefix) {
+			continue
+		}
+
+		prefix := strings.TrimSuffix(strings.TrimPrefix(name, reportTemplateFilePrefix), reportTemplateFileSuffix)
+
+		tmpl, err := template.New(name).Parse(string(content))
+		if err != nil {
+			log.Error().Err(err).Str("file", name).Msg("Unable to parse report template")
+			return nil, err
+		}
+
+		log.Info().Str("file", name).Str("prefix", prefix).Msg("Report template loaded")
+		templates[prefix] = tmpl
+	}
+
+	return templates, nil
+}
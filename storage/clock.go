@@ -0,0 +1,44 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"time"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/clock"
+)
+
+// Clock returns clock used by all time dependent parts of storage
+func (storage MemoryStorage) Clock() clock.Clock {
+	return storage.clock
+}
+
+// now returns current time according to the clock used by storage
+func (storage MemoryStorage) now() time.Time {
+	return storage.clock.Now()
+}
+
+// TimestampClock returns clock used for timestamps generated by storage, it
+// differs from Clock by configured clock skew
+func (storage MemoryStorage) TimestampClock() clock.Clock {
+	return storage.timestampClock
+}
+
+// timestampNow returns current time to be used in generated timestamps
+func (storage MemoryStorage) timestampNow() time.Time {
+	return storage.timestampClock.Now()
+}
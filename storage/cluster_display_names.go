@@ -0,0 +1,111 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// Human-friendly display names of clusters can be specified in file with this
+// name stored in data directory. The file contains JSON object with display
+// names as keys and cluster UUIDs as values.
+const clusterDisplayNamesFileName = "cluster_names.json"
+
+// display names are replaced as a whole on reload
+var (
+	clusterDisplayNames      = make(map[string]types.ClusterName)
+	clusterDisplayNamesMutex sync.RWMutex
+)
+
+// loadClusterDisplayNames reads mapping between display names and cluster
+// UUIDs from data directory. No display names are used when the file does
+// not exist or when data are read from tar.gz archive.
+func loadClusterDisplayNames(path string) (map[string]types.ClusterName, error) {
+	names := make(map[string]types.ClusterName)
+	if isArchive(path) {
+		return names, nil
+	}
+
+	content, err := fs.ReadFile(dataFiles(path), clusterDisplayNamesFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return names, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed map[string]string
+	err = json.Unmarshal(content, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", clusterDisplayNamesFileName, err)
+	}
+
+	for name, cluster := range parsed {
+		// display name that looks like UUID would hide the real cluster
+		if _, err := ValidateClusterName(name); err == nil {
+			return nil, fmt.Errorf("display name %q in %s collides with cluster UUID", name, clusterDisplayNamesFileName)
+		}
+
+		clusterName, err := ValidateClusterName(cluster)
+		if err != nil {
+			return nil, fmt.Errorf("improper cluster for display name %q in %s: %v", name, clusterDisplayNamesFileName, err)
+		}
+		names[name] = clusterName
+	}
+
+	log.Info().Int("names", len(names)).Msg("Cluster display names loaded")
+	return names, nil
+}
+
+// loadedClusterDisplayNames returns currently used mapping between display
+// names and cluster UUIDs
+func loadedClusterDisplayNames() map[string]types.ClusterName {
+	clusterDisplayNamesMutex.RLock()
+	defer clusterDisplayNamesMutex.RUnlock()
+	return clusterDisplayNames
+}
+
+// swapClusterDisplayNames replaces mapping between display names and
+// cluster UUIDs
+func swapClusterDisplayNames(newNames map[string]types.ClusterName) {
+	clusterDisplayNamesMutex.Lock()
+	defer clusterDisplayNamesMutex.Unlock()
+	clusterDisplayNames = newNames
+}
+
+// ResolveClusterName returns cluster UUID for given cluster UUID or display
+// name. Error is returned when the value is neither UUID nor known display
+// name.
+func (storage MemoryStorage) ResolveClusterName(nameOrUUID string) (types.ClusterName, error) {
+	if clusterName, found := loadedClusterDisplayNames()[nameOrUUID]; found {
+		return clusterName, nil
+	}
+
+	clusterName, err := ValidateClusterName(nameOrUUID)
+	if err != nil {
+		return "", fmt.Errorf("cluster name %q is neither UUID nor known display name", nameOrUUID)
+	}
+	return clusterName, nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"regexp"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// Information about cluster can be stored in file named
+// info_{cluster}.json in data directory
+const (
+	clusterInfoFileMatch = "info_*.json"
+
+	// DefaultClusterVersion is version used for clusters without info file
+	DefaultClusterVersion = "4.5.0"
+)
+
+// clusterInfoFileRegexp matches name of file with cluster info and captures
+// cluster name
+var clusterInfoFileRegexp = regexp.MustCompile(
+	`^info_([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})\.json$`)
+
+// ClusterInfo contains information about cluster returned in report
+// metadata. LastCheckedAt overrides the value stored in report when set.
+type ClusterInfo struct {
+	ClusterVersion string `json:"cluster_version"`
+	Managed        bool   `json:"managed"`
+	LastCheckedAt  string `json:"last_checked_at,omitempty"`
+}
+
+// cluster info is replaced as a whole on reload
+var (
+	clusterInfo      = make(map[types.ClusterName]ClusterInfo)
+	clusterInfoMutex sync.RWMutex
+)
+
+// loadClusterInfo reads information about clusters from data directory.
+// Files that can't be read or parsed are skipped. Nothing is read from
+// tar.gz archive.
+func loadClusterInfo(path string) map[types.ClusterName]ClusterInfo {
+	info := make(map[types.ClusterName]ClusterInfo)
+	if isArchive(path) {
+		return info
+	}
+
+	files := dataFiles(path)
+	names, err := fs.Glob(files, clusterInfoFileMatch)
+	if err != nil {
+		log.Warn().Err(err).Msg("Cluster info can't be read")
+	}
+
+	for _, name := range names {
+		matches := clusterInfoFileRegexp.FindStringSubmatch(name)
+		if matches == nil {
+			continue
+		}
+
+		content, err := fs.ReadFile(files, name)
+		var parsed ClusterInfo
+		if err == nil {
+			err = json.Unmarshal(content, &parsed)
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("file", name).Msg("Cluster info is skipped")
+			continue
+		}
+
+		info[NormalizeClusterName(types.ClusterName(matches[1]))] = parsed
+	}
+
+	log.Info().Int("clusters", len(info)).Msg("Cluster info loaded")
+	return info
+}
+
+// swapClusterInfo replaces currently loaded information about clusters
+func swapClusterInfo(newInfo map[types.ClusterName]ClusterInfo) {
+	clusterInfoMutex.Lock()
+	defer clusterInfoMutex.Unlock()
+	clusterInfo = newInfo
+}
+
+// ClusterInfo returns information about given cluster read from its info
+// file, placeholder values are returned for cluster without info file
+func (storage MemoryStorage) ClusterInfo(clusterName types.ClusterName) ClusterInfo {
+	clusterInfoMutex.RLock()
+	defer clusterInfoMutex.RUnlock()
+
+	info, found := clusterInfo[NormalizeClusterName(clusterName)]
+	if !found {
+		return ClusterInfo{ClusterVersion: DefaultClusterVersion}
+	}
+	if info.ClusterVersion == "" {
+		info.ClusterVersion = DefaultClusterVersion
+	}
+	return info
+}
+
+// AddClusterInfoToReport returns copy of given report with cluster info
+// stored in report metadata. Other metadata (like number of rule hits) are
+// kept.
+func AddClusterInfoToReport(report types.ClusterReport, info ClusterInfo) (types.ClusterReport, error) {
+	var parsed map[string]interface{}
+	err := json.Unmarshal([]byte(report), &parsed)
+	if err != nil {
+		return report, err
+	}
+
+	reports, ok := parsed["reports"].(map[string]interface{})
+	if !ok {
+		return report, errors.New("report does not contain 'reports' object")
+	}
+
+	meta, ok := reports["meta"].(map[string]interface{})
+	if !ok {
+		meta = make(map[string]interface{})
+		reports["meta"] = meta
+	}
+	meta["cluster_version"] = info.ClusterVersion
+	meta["managed"] = info.Managed
+	if info.LastCheckedAt != "" {
+		meta["last_checked_at"] = info.LastCheckedAt
+	}
+
+	transformed, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return report, err
+	}
+
+	return types.ClusterReport(transformed), nil
+}
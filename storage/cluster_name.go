@@ -0,0 +1,44 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// cluster names are UUIDs, letters are accepted in any case
+var clusterNameRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// NormalizeClusterName converts cluster name into canonical (lowercase) form
+// that is used as key for stored reports
+func NormalizeClusterName(clusterName types.ClusterName) types.ClusterName {
+	return types.ClusterName(strings.ToLower(string(clusterName)))
+}
+
+// ValidateClusterName checks that cluster name is UUID and returns it in
+// canonical form
+func ValidateClusterName(clusterName string) (types.ClusterName, error) {
+	if !clusterNameRegexp.MatchString(clusterName) {
+		return "", fmt.Errorf("cluster name %q is not a proper UUID", clusterName)
+	}
+
+	return NormalizeClusterName(types.ClusterName(clusterName)), nil
+}
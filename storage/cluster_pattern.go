@@ -0,0 +1,51 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// ClustersMatchingPattern returns sorted list of all clusters with loaded
+// report whose name matches given glob pattern, for example 00000001-*
+func (storage MemoryStorage) ClustersMatchingPattern(glob string) ([]types.ClusterName, error) {
+	glob = strings.ToLower(glob)
+
+	// check the pattern itself, so malformed pattern is detected even when
+	// no reports are loaded
+	_, err := path.Match(glob, "")
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]types.ClusterName, 0)
+	for cluster := range loadedReports() {
+		matches, _ := path.Match(glob, cluster)
+		if matches {
+			clusters = append(clusters, types.ClusterName(cluster))
+		}
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i] < clusters[j]
+	})
+	return clusters, nil
+}
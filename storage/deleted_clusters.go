@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"sync"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// deletedClusterSet contains clusters that are treated as deleted, i.e. as
+// if no report exists for them even when report file is available.
+// Configured clusters are used when the service starts and after reset, more
+// clusters can be deleted at runtime.
+type deletedClusterSet struct {
+	configured []types.ClusterName
+	clusters   map[types.ClusterName]bool
+	mutex      sync.RWMutex
+}
+
+// newDeletedClusterSet constructs set containing configured deleted clusters
+func newDeletedClusterSet(configured []types.ClusterName) *deletedClusterSet {
+	set := &deletedClusterSet{configured: configured}
+	set.reset()
+	return set
+}
+
+// reset restores configured deleted clusters, the caller needs to hold the
+// lock when the set is shared already
+func (set *deletedClusterSet) reset() {
+	set.clusters = make(map[types.ClusterName]bool, len(set.configured))
+	for _, cluster := range set.configured {
+		set.clusters[NormalizeClusterName(cluster)] = true
+	}
+}
+
+// IsDeletedCluster checks if given cluster is treated as deleted
+func (storage MemoryStorage) IsDeletedCluster(clusterName types.ClusterName) bool {
+	storage.deletedClusters.mutex.RLock()
+	defer storage.deletedClusters.mutex.RUnlock()
+	return storage.deletedClusters.clusters[NormalizeClusterName(clusterName)]
+}
+
+// withoutDeletedClusters returns given clusters except the deleted ones
+func (storage MemoryStorage) withoutDeletedClusters(clusters []types.ClusterName) []types.ClusterName {
+	filtered := make([]types.ClusterName, 0, len(clusters))
+	for _, cluster := range clusters {
+		if !storage.IsDeletedCluster(cluster) {
+			filtered = append(filtered, cluster)
+		}
+	}
+	return filtered
+}
+
+// SetClusterDeleted marks given cluster as deleted or restores it. The
+// change affects all subsequent requests.
+func (storage MemoryStorage) SetClusterDeleted(clusterName types.ClusterName, deleted bool) {
+	storage.deletedClusters.mutex.Lock()
+	defer storage.deletedClusters.mutex.Unlock()
+
+	clusterName = NormalizeClusterName(clusterName)
+	if deleted {
+		storage.deletedClusters.clusters[clusterName] = true
+	} else {
+		delete(storage.deletedClusters.clusters, clusterName)
+	}
+	reportsChanged()
+}
+
+// ResetDeletedClusters restores the configured set of deleted clusters
+func (storage MemoryStorage) ResetDeletedClusters() {
+	storage.deletedClusters.mutex.Lock()
+	defer storage.deletedClusters.mutex.Unlock()
+	storage.deletedClusters.reset()
+	reportsChanged()
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// MaxReportInflation is the maximum number of synthetic rule hits that can
+// be added into one report. Each synthetic hit has roughly the same size as
+// the first rule hit in the original report (usually 2-10 kB), so the
+// inflated report can have tens of megabytes.
+const MaxReportInflation = 10000
+
+// syntheticRuleHit is used when the inflated report does not contain any
+// rule hit that could be cloned
+var syntheticRuleHit = map[string]interface{}{
+	"created_at":  "2020-01-01T00:00:00Z",
+	"description": "Synthetic rule hit",
+	"details": map[string]interface{}{
+		"type":      "rule",
+		"error_key": "INFLATED",
+	},
+	"disabled":       false,
+	"extra_data":     map[string]interface{}{},
+	"reason":         "Synthetic rule hit added to inflate the report",
+	"resolution":     "No resolution is needed",
+	"risk_of_change": 0,
+	"rule_id":        "ccx_rules_ocp.external.rules.inflated",
+	"tags":           []interface{}{},
+	"total_risk":     1,
+	"user_vote":      0,
+}
+
+// InflateReport returns copy of given report extended by count synthetic
+// rule hits. Synthetic hits are clones of the first rule hit found in the
+// report with unique rule ID and error key.
+func InflateReport(report types.ClusterReport, count int) (types.ClusterReport, error) {
+	if count < 0 || count > MaxReportInflation {
+		return report, fmt.Errorf("number of synthetic rule hits needs to be in range 0..%d", MaxReportInflation)
+	}
+	if count == 0 {
+		return report, nil
+	}
+
+	return transformReportRuleHits(report, func(hits []interface{}) []interface{} {
+		base := syntheticRuleHit
+		if len(hits) > 0 {
+			if first, ok := hits[0].(map[string]interface{}); ok {
+				base = first
+			}
+		}
+
+		for i := 1; i <= count; i++ {
+			hits = append(hits, cloneRuleHit(base, i))
+		}
+		return hits
+	})
+}
+
+// cloneRuleHit makes deep copy of rule hit with unique rule ID and error key
+// derived from given index
+func cloneRuleHit(hit map[string]interface{}, index int) map[string]interface{} {
+	clone := deepCopy(hit).(map[string]interface{})
+
+	clone["rule_id"] = fmt.Sprintf("%v.synthetic_%d", hit["rule_id"], index)
+
+	details, ok := clone["details"].(map[string]interface{})
+	if !ok {
+		details = map[string]interface{}{}
+		clone["details"] = details
+	}
+	details["error_key"] = fmt.Sprintf("%v_SYNTHETIC_%d", details["error_key"], index)
+
+	return clone
+}
+
+// deepCopy makes deep copy of value decoded from JSON, so nested objects and
+// arrays are not shared between the original and the copy
+func deepCopy(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, item := range value {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
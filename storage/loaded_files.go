@@ -0,0 +1,124 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// LoadedFile represents report file that has been loaded into storage
+type LoadedFile struct {
+	Cluster types.ClusterName `json:"cluster"`
+	Name    string            `json:"name"`
+	Size    int64             `json:"size"`
+	ModTime time.Time         `json:"mtime"`
+}
+
+// metadata about report files captured during the last (re)load, time of
+// the last change of each report is initialized from modification times of
+// the files
+var (
+	loadedFiles      []LoadedFile = []LoadedFile{}
+	reportModTimes                = make(map[types.ClusterName]time.Time)
+	loadedFilesMutex sync.RWMutex
+)
+
+// describeLoadedFiles returns sorted list of files with given loaded reports.
+// Files stored in tar.gz archive have modification time of the archive
+// itself, and files from embedded dataset have no modification time.
+func describeLoadedFiles(path string, loaded map[string]string) []LoadedFile {
+	files := make([]LoadedFile, 0, len(loaded))
+
+	if isArchive(path) {
+		var modTime time.Time
+		if info, err := os.Stat(path); err == nil {
+			modTime = info.ModTime()
+		}
+		for cluster, report := range loaded {
+			files = append(files, LoadedFile{
+				Cluster: types.ClusterName(cluster),
+				Name:    path + ":" + reportFileName(cluster),
+				Size:    int64(len(report)),
+				ModTime: modTime,
+			})
+		}
+	} else {
+		dataFS := dataFiles(path)
+		for cluster, report := range loaded {
+			file := LoadedFile{
+				Cluster: types.ClusterName(cluster),
+				Name:    reportFileName(cluster),
+				Size:    int64(len(report)),
+			}
+			if info, err := fs.Stat(dataFS, file.Name); err == nil {
+				file.Size = info.Size()
+				file.ModTime = info.ModTime()
+			}
+			files = append(files, file)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files
+}
+
+// swapLoadedFiles replaces metadata about loaded report files
+func swapLoadedFiles(newFiles []LoadedFile) {
+	modTimes := make(map[types.ClusterName]time.Time, len(newFiles))
+	for _, file := range newFiles {
+		modTimes[file.Cluster] = file.ModTime
+	}
+
+	loadedFilesMutex.Lock()
+	defer loadedFilesMutex.Unlock()
+	loadedFiles = newFiles
+	reportModTimes = modTimes
+}
+
+// touchReport records that report for given cluster has been changed now
+func (storage MemoryStorage) touchReport(clusterName types.ClusterName) {
+	loadedFilesMutex.Lock()
+	defer loadedFilesMutex.Unlock()
+	reportModTimes[clusterName] = storage.now()
+}
+
+// ReportChangedSince checks if report for given cluster has been changed
+// after given time. Reports not stored in files (for example reports
+// generated from templates) are never considered changed.
+func (storage MemoryStorage) ReportChangedSince(clusterName types.ClusterName, since time.Time) bool {
+	loadedFilesMutex.RLock()
+	defer loadedFilesMutex.RUnlock()
+
+	modTime, found := reportModTimes[NormalizeClusterName(clusterName)]
+	return found && modTime.After(since)
+}
+
+// LoadedFiles returns list of report files loaded during the last (re)load
+// together with their sizes and modification times
+func (storage MemoryStorage) LoadedFiles() []LoadedFile {
+	loadedFilesMutex.RLock()
+	defer loadedFilesMutex.RUnlock()
+	return loadedFiles
+}
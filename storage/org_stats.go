@@ -0,0 +1,149 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// RuleHitCount represents number of clusters hitting given rule
+type RuleHitCount struct {
+	RuleID      types.RuleID   `json:"rule_id"`
+	ErrorKey    types.ErrorKey `json:"error_key"`
+	ClustersHit int            `json:"clusters_hit"`
+}
+
+// OrgRuleHitStats represents rule hit statistics for all clusters that
+// belong to one organization
+type OrgRuleHitStats struct {
+	OrgID    types.OrgID    `json:"organization"`
+	Clusters int            `json:"clusters"`
+	Rules    []RuleHitCount `json:"rules"`
+}
+
+// cachedOrgRuleHitStats contains computed statistics together with reports
+// generation and variants of "changing clusters" they were computed from
+type cachedOrgRuleHitStats struct {
+	generation uint64
+	variants   string
+	stats      OrgRuleHitStats
+}
+
+// statistics are computed from all reports of organization, so they are
+// cached until reports are changed or "changing clusters" are rotated
+var (
+	orgRuleHitStatsCache      = make(map[types.OrgID]cachedOrgRuleHitStats)
+	orgRuleHitStatsCacheMutex sync.Mutex
+)
+
+// RuleHitStatsForOrg returns number of clusters hitting each rule for all
+// clusters that belong to given organization
+func (storage MemoryStorage) RuleHitStatsForOrg(orgID types.OrgID) (OrgRuleHitStats, error) {
+	generation := loadedReportsGeneration()
+	variants := storage.changingClustersVariants()
+
+	orgRuleHitStatsCacheMutex.Lock()
+	cached, found := orgRuleHitStatsCache[orgID]
+	orgRuleHitStatsCacheMutex.Unlock()
+
+	if found && cached.generation == generation && cached.variants == variants {
+		return cached.stats, nil
+	}
+
+	stats, err := storage.computeRuleHitStatsForOrg(orgID)
+	if err != nil {
+		return stats, err
+	}
+
+	orgRuleHitStatsCacheMutex.Lock()
+	orgRuleHitStatsCache[orgID] = cachedOrgRuleHitStats{
+		generation: generation,
+		variants:   variants,
+		stats:      stats,
+	}
+	orgRuleHitStatsCacheMutex.Unlock()
+
+	return stats, nil
+}
+
+// computeRuleHitStatsForOrg walks through reports of all clusters that belong
+// to given organization and counts clusters hitting each rule
+func (storage MemoryStorage) computeRuleHitStatsForOrg(orgID types.OrgID) (OrgRuleHitStats, error) {
+	clusters, err := storage.ListOfClustersForOrg(orgID)
+	if err != nil {
+		return OrgRuleHitStats{}, err
+	}
+
+	counts := make(map[types.RuleSelector]*RuleHitCount)
+
+	for _, cluster := range clusters {
+		report, err := storage.ReadReportForCluster(cluster)
+		if err != nil || report == "" {
+			continue
+		}
+
+		hits, err := ParseReportRuleHits(report)
+		if err != nil {
+			log.Error().Err(err).Str("cluster", string(cluster)).Msg("Unable to parse report")
+			continue
+		}
+
+		// one cluster is counted only once for each rule
+		seen := make(map[types.RuleSelector]bool)
+		for _, hit := range hits {
+			selector := types.RuleSelector(string(hit.RuleID) + "|" + string(hit.Details.ErrorKey))
+			if seen[selector] {
+				continue
+			}
+			seen[selector] = true
+
+			count, found := counts[selector]
+			if !found {
+				count = &RuleHitCount{RuleID: hit.RuleID, ErrorKey: hit.Details.ErrorKey}
+				counts[selector] = count
+			}
+			count.ClustersHit++
+		}
+	}
+
+	rules := make([]RuleHitCount, 0, len(counts))
+	for _, count := range counts {
+		rules = append(rules, *count)
+	}
+
+	// the most frequently hit rules first
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].ClustersHit != rules[j].ClustersHit {
+			return rules[i].ClustersHit > rules[j].ClustersHit
+		}
+		if rules[i].RuleID != rules[j].RuleID {
+			return rules[i].RuleID < rules[j].RuleID
+		}
+		return rules[i].ErrorKey < rules[j].ErrorKey
+	})
+
+	return OrgRuleHitStats{
+		OrgID:    orgID,
+		Clusters: len(clusters),
+		Rules:    rules,
+	}, nil
+}
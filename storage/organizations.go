@@ -0,0 +1,291 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// Mapping between organizations and clusters can be overridden by file with
+// this name stored in data directory. The file contains JSON object with
+// organization IDs as keys and lists of cluster names as values. One cluster
+// can be listed in more organizations.
+const organizationsFileName = "organizations.json"
+
+// organization that is not allowed to be accessed by anyone by default, more
+// organizations can be denied at runtime
+const forbiddenOrgID = types.OrgID(11940171)
+
+var (
+	errNoPermissions   = errors.New("You have no permissions to get or change info about this organization")
+	errClusterNotInOrg = errors.New("Cluster does not belong to this organization")
+)
+
+// organizations maps organization ID to list of clusters owned by the
+// organization
+type organizations map[types.OrgID][]types.ClusterName
+
+var (
+	loadedOrgs organizations = defaultOrganizations()
+	orgsMutex  sync.RWMutex
+)
+
+// set of organizations that can't be accessed
+var (
+	deniedOrgs      = defaultDeniedOrganizations()
+	deniedOrgsMutex sync.RWMutex
+)
+
+// defaultDeniedOrganizations returns set of organizations that can't be
+// accessed when the service starts
+func defaultDeniedOrganizations() map[types.OrgID]bool {
+	return map[types.OrgID]bool{forbiddenOrgID: true}
+}
+
+// defaultOrganizations returns mapping used when no organizations file is
+// available, each cluster is owned by exactly one organization
+func defaultOrganizations() organizations {
+	return organizations{
+		11789772: {
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a266",
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a267",
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a268",
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a269",
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a26a",
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a26b",
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a26c",
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a26d",
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a26e",
+			"34c3ecc5-624a-49a5-bab8-4fdc5e51a26f",
+			"74ae54aa-6577-4e80-85e7-697cb646ff37",
+			"a7467445-8d6a-43cc-b82c-7007664bdf69",
+			"ee7d2bf4-8933-4a3a-8634-3328fe806e08",
+			"eeeeeeee-eeee-eeee-eeee-000000000001",
+		},
+		1: {
+			"00000001-624a-49a5-bab8-4fdc5e51a266",
+			"00000001-624a-49a5-bab8-4fdc5e51a267",
+			"00000001-624a-49a5-bab8-4fdc5e51a268",
+			"00000001-624a-49a5-bab8-4fdc5e51a269",
+			"00000001-624a-49a5-bab8-4fdc5e51a26a",
+			"00000001-624a-49a5-bab8-4fdc5e51a26b",
+			"00000001-624a-49a5-bab8-4fdc5e51a26c",
+			"00000001-624a-49a5-bab8-4fdc5e51a26d",
+			"00000001-624a-49a5-bab8-4fdc5e51a26e",
+			"00000001-624a-49a5-bab8-4fdc5e51a26f",
+			"00000001-6577-4e80-85e7-697cb646ff37",
+			"00000001-8933-4a3a-8634-3328fe806e08",
+			"00000001-8d6a-43cc-b82c-7007664bdf69",
+			"00000001-eeee-eeee-eeee-000000000001",
+		},
+		2: {
+			"00000002-624a-49a5-bab8-4fdc5e51a266",
+			"00000002-6577-4e80-85e7-697cb646ff37",
+			"00000002-8933-4a3a-8634-3328fe806e08",
+		},
+		3: {
+			"00000003-8933-4a3a-8634-3328fe806e08",
+			"00000003-8d6a-43cc-b82c-7007664bdf69",
+			"00000003-eeee-eeee-eeee-000000000001",
+		},
+		// known organization without any cluster
+		5: {},
+	}
+}
+
+// loadOrganizations reads mapping between organizations and clusters from
+// data directory. Default mapping is used when the file does not exist or
+// when data are read from tar.gz archive.
+func loadOrganizations(path string) (organizations, error) {
+	if isArchive(path) {
+		return defaultOrganizations(), nil
+	}
+
+	content, err := fs.ReadFile(dataFiles(path), organizationsFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defaultOrganizations(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed map[string][]types.ClusterName
+	err = json.Unmarshal(content, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", organizationsFileName, err)
+	}
+
+	orgs := make(organizations, len(parsed))
+	for key, clusters := range parsed {
+		orgID, err := strconv.ParseUint(key, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("improper organization ID %q in %s", key, organizationsFileName)
+		}
+		orgs[types.OrgID(orgID)] = clusters
+	}
+
+	log.Info().Int("organizations", len(orgs)).Msg("Organizations loaded")
+	return orgs, nil
+}
+
+// loadedOrganizations returns currently used mapping between organizations
+// and clusters
+func loadedOrganizations() organizations {
+	orgsMutex.RLock()
+	defer orgsMutex.RUnlock()
+	return loadedOrgs
+}
+
+// swapOrganizations replaces mapping between organizations and clusters
+func swapOrganizations(newOrgs organizations) {
+	orgsMutex.Lock()
+	defer orgsMutex.Unlock()
+	loadedOrgs = newOrgs
+	reportsChanged()
+}
+
+// isForbiddenOrg checks if given organization can't be accessed
+func isForbiddenOrg(orgID types.OrgID) bool {
+	deniedOrgsMutex.RLock()
+	defer deniedOrgsMutex.RUnlock()
+	return deniedOrgs[orgID]
+}
+
+// deniedOrganizations returns list of all organizations that can't be
+// accessed
+func deniedOrganizations() []types.OrgID {
+	deniedOrgsMutex.RLock()
+	defer deniedOrgsMutex.RUnlock()
+
+	denied := make([]types.OrgID, 0, len(deniedOrgs))
+	for orgID := range deniedOrgs {
+		denied = append(denied, orgID)
+	}
+	return denied
+}
+
+// SetOrganizationDenied denies or allows access to given organization. The
+// change affects all subsequent requests.
+func (storage MemoryStorage) SetOrganizationDenied(orgID types.OrgID, denied bool) {
+	deniedOrgsMutex.Lock()
+	defer deniedOrgsMutex.Unlock()
+
+	if denied {
+		deniedOrgs[orgID] = true
+	} else {
+		delete(deniedOrgs, orgID)
+	}
+	reportsChanged()
+}
+
+// ResetDeniedOrganizations restores the default set of organizations that
+// can't be accessed
+func (storage MemoryStorage) ResetDeniedOrganizations() {
+	deniedOrgsMutex.Lock()
+	defer deniedOrgsMutex.Unlock()
+	deniedOrgs = defaultDeniedOrganizations()
+	reportsChanged()
+}
+
+// IsKnownOrganization checks if given organization exists, even when it
+// does not own any cluster
+func (storage MemoryStorage) IsKnownOrganization(orgID types.OrgID) bool {
+	if _, found := loadedOrganizations()[orgID]; found {
+		return true
+	}
+
+	return orgID == storage.syntheticClustersOrgID && storage.syntheticClustersCount > 0
+}
+
+// KnownOrganizations returns sorted list of all known organizations,
+// including organizations that can't be accessed
+func (storage MemoryStorage) KnownOrganizations() []types.OrgID {
+	known := deniedOrganizations()
+
+	listed, _ := storage.ListOfOrgs()
+	for _, orgID := range listed {
+		if !containsOrg(known, orgID) {
+			known = append(known, orgID)
+		}
+	}
+
+	for orgID := range loadedOrganizations() {
+		if !containsOrg(known, orgID) {
+			known = append(known, orgID)
+		}
+	}
+
+	if storage.syntheticClustersCount > 0 && !containsOrg(known, storage.syntheticClustersOrgID) {
+		known = append(known, storage.syntheticClustersOrgID)
+	}
+
+	sort.Slice(known, func(i, j int) bool {
+		return known[i] < known[j]
+	})
+	return known
+}
+
+// ownersOfCluster returns sorted list of all organizations owning given
+// cluster
+func (storage MemoryStorage) ownersOfCluster(clusterName types.ClusterName) []types.OrgID {
+	owners := make([]types.OrgID, 0)
+
+	for orgID, clusters := range loadedOrganizations() {
+		if containsCluster(clusters, clusterName) {
+			owners = append(owners, orgID)
+		}
+	}
+
+	if orgID, _, synthetic := storage.parseSyntheticCluster(clusterName); synthetic {
+		owners = append(owners, orgID)
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		return owners[i] < owners[j]
+	})
+	return owners
+}
+
+// containsCluster checks if given cluster is in the list
+func containsCluster(clusters []types.ClusterName, clusterName types.ClusterName) bool {
+	for _, cluster := range clusters {
+		if cluster == clusterName {
+			return true
+		}
+	}
+	return false
+}
+
+// containsOrg checks if given organization is in the list
+func containsOrg(orgs []types.OrgID, orgID types.OrgID) bool {
+	for _, org := range orgs {
+		if org == orgID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// Report can be stored in data directory in gzip-compressed form as well,
+// in file with this suffix appended to name of the plain report file
+const compressedReportFileSuffix = ".gz"
+
+// precompressedReport contains compressed report together with the plain
+// report it has been compressed from
+type precompressedReport struct {
+	plain      string
+	compressed []byte
+}
+
+// precompressed reports are replaced as a whole on reload
+var (
+	precompressedReports      = make(map[types.ClusterName]precompressedReport)
+	precompressedReportsMutex sync.RWMutex
+)
+
+// loadPrecompressedReports reads compressed variants of given loaded reports
+// from data directory. Compressed files that can't be read or that don't
+// match the plain report are skipped. Nothing is read from tar.gz archive.
+func loadPrecompressedReports(path string, loaded map[string]string) map[types.ClusterName]precompressedReport {
+	precompressed := make(map[types.ClusterName]precompressedReport)
+	if isArchive(path) {
+		return precompressed
+	}
+
+	files := dataFiles(path)
+	for cluster, report := range loaded {
+		name := reportFileName(cluster) + compressedReportFileSuffix
+
+		compressed, err := fs.ReadFile(files, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			err = checkCompressedReport(compressed, report)
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("file", name).Msg("Compressed report is skipped")
+			continue
+		}
+
+		precompressed[types.ClusterName(cluster)] = precompressedReport{
+			plain:      report,
+			compressed: compressed,
+		}
+	}
+
+	log.Info().Int("reports", len(precompressed)).Msg("Compressed reports loaded")
+	return precompressed
+}
+
+// checkCompressedReport checks that compressed data contain given report
+func checkCompressedReport(compressed []byte, report string) error {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return err
+	}
+
+	// disable "G110 (CWE-409): Potential DoS vulnerability via decompression bomb"
+	// #nosec G110
+	decompressed, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	if string(decompressed) != report {
+		return errors.New("compressed report differs from plain report")
+	}
+	return nil
+}
+
+// swapPrecompressedReports replaces currently loaded compressed reports
+func swapPrecompressedReports(newReports map[types.ClusterName]precompressedReport) {
+	precompressedReportsMutex.Lock()
+	defer precompressedReportsMutex.Unlock()
+	precompressedReports = newReports
+}
+
+// PrecompressedReport returns gzip-compressed variant of given report for
+// given cluster. The compressed variant is returned only when the report has
+// not been modified since it was loaded.
+func (storage MemoryStorage) PrecompressedReport(clusterName types.ClusterName, report types.ClusterReport) ([]byte, bool) {
+	precompressedReportsMutex.RLock()
+	defer precompressedReportsMutex.RUnlock()
+
+	precompressed, found := precompressedReports[clusterName]
+	if !found || precompressed.plain != string(report) {
+		return nil, false
+	}
+	return precompressed.compressed, true
+}
@@ -0,0 +1,156 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ReloadFailure represents data file that could not be (re)loaded
+type ReloadFailure struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+
+	cluster string
+	cause   error
+}
+
+// ReloadResult represents result of reloading data files
+type ReloadResult struct {
+	ReportsCount         int             `json:"reports_count"`
+	ReportTemplatesCount int             `json:"report_templates_count"`
+	Failures             []ReloadFailure `json:"failures"`
+}
+
+// newReloadFailure constructs ReloadFailure for given file and error
+func newReloadFailure(file string, cluster string, err error) ReloadFailure {
+	return ReloadFailure{
+		File:    file,
+		Error:   err.Error(),
+		cluster: cluster,
+		cause:   err,
+	}
+}
+
+// readReportsAndTemplates reads reports for all clusters and report
+// templates from given directory (or the embedded dataset) or tar.gz
+// archive. Report files that can't be read or that don't contain valid JSON
+// are returned as failures, nil map of reports is returned when the archive
+// itself can't be read.
+func readReportsAndTemplates(path string) (map[string]string, []ReloadFailure, map[string]*template.Template, error) {
+	if isArchive(path) {
+		return readReportsAndTemplatesFromArchive(path)
+	}
+
+	loaded, failures := readReports(path)
+	templates, err := loadReportTemplates(path)
+	return loaded, failures, templates, err
+}
+
+// readReports reads reports for all clusters from given directory (or the
+// embedded dataset). Files that can't be read or that don't contain valid
+// JSON are returned as failures.
+func readReports(path string) (map[string]string, []ReloadFailure) {
+	files := dataFiles(path)
+	loaded := make(map[string]string)
+	failures := []ReloadFailure{}
+
+	for _, cluster := range clustersWithReportFiles() {
+		report, err := readReport(files, cluster)
+		if err == nil && !json.Valid([]byte(report)) {
+			err = fmt.Errorf("report for cluster %s is not valid JSON", cluster)
+		}
+		if err != nil {
+			failures = append(failures, newReloadFailure(reportFileName(cluster), cluster, err))
+			continue
+		}
+		loaded[cluster] = report
+	}
+
+	return loaded, failures
+}
+
+// Reload re-reads all reports, report templates, organizations, rule
+// content and cluster display names from data
+// directory and replaces loaded data at once. When a file can't be reloaded, previously
+// loaded data are kept for it.
+func (storage MemoryStorage) Reload() ReloadResult {
+	loaded, failures, templates, templatesErr := readReportsAndTemplates(storage.path)
+
+	previous := loadedReports()
+	if loaded == nil {
+		// data source as a whole is not readable, so all previously
+		// loaded reports are kept
+		loaded = previous
+	}
+	for _, failure := range failures {
+		log.Error().Err(failure.cause).Str("file", failure.File).Msg("Unable to reload report")
+		if report, found := previous[failure.cluster]; found {
+			loaded[failure.cluster] = report
+		}
+	}
+
+	if templatesErr != nil {
+		failures = append(failures, newReloadFailure(storage.path, "", templatesErr))
+		templates = loadedReportTemplates()
+	}
+
+	orgs, err := loadOrganizations(storage.path)
+	if err != nil {
+		failures = append(failures, newReloadFailure(organizationsFileName, "", err))
+		orgs = loadedOrganizations()
+	}
+
+	content, err := loadRuleContent(storage.path)
+	if err != nil {
+		failures = append(failures, newReloadFailure(ruleContentDirName, "", err))
+		content = loadedRuleContent()
+	}
+
+	translations, err := loadRuleContentTranslations(storage.path)
+	if err != nil {
+		failures = append(failures, newReloadFailure(ruleContentDirName, "", err))
+		translations = loadedRuleContentTranslations()
+	}
+
+	displayNames, err := loadClusterDisplayNames(storage.path)
+	if err != nil {
+		failures = append(failures, newReloadFailure(clusterDisplayNamesFileName, "", err))
+		displayNames = loadedClusterDisplayNames()
+	}
+
+	swapRuleContent(content)
+	swapRuleContentTranslations(translations)
+	swapReports(loaded, templates)
+	swapLoadedFiles(describeLoadedFiles(storage.path, loaded))
+	swapPrecompressedReports(loadPrecompressedReports(storage.path, loaded))
+	swapReportHistory(loadReportHistory(storage.path))
+	swapClusterInfo(loadClusterInfo(storage.path))
+	swapOrganizations(orgs)
+	swapClusterDisplayNames(displayNames)
+	log.Info().Int("reports", len(loaded)).Int("failures", len(failures)).Msg("Data files reloaded")
+
+	return ReloadResult{
+		ReportsCount:         len(loaded),
+		ReportTemplatesCount: len(templates),
+		Failures:             failures,
+	}
+}
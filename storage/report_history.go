@@ -0,0 +1,182 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"io/fs"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// Historical reports are stored in files named
+// report_{cluster}_{timestamp}.json, where timestamp is in basic ISO 8601
+// format in UTC, for example 20200527T141535Z (colons are not allowed in
+// file names embedded into the service)
+const (
+	reportHistoryFileMatch = reportFilePrefix + "*_*" + reportFileSuffix
+	snapshotTimeFormat     = "20060102T150405Z"
+)
+
+// reportHistoryFileRegexp matches name of file with historical report and
+// captures cluster name and timestamp
+var reportHistoryFileRegexp = regexp.MustCompile(
+	`^report_([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})_([0-9]{8}T[0-9]{6}Z)\.json$`)
+
+// ReportSnapshot is report for one cluster as it was at given time
+type ReportSnapshot struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Report    json.RawMessage `json:"report"`
+}
+
+// reportHistory maps cluster to its snapshots ordered from the oldest one,
+// the map is replaced as a whole on reload
+var (
+	reportHistory      = make(map[types.ClusterName][]ReportSnapshot)
+	reportHistoryMutex sync.RWMutex
+)
+
+// isReportHistoryFile checks if given file name belongs to historical report
+func isReportHistoryFile(name string) bool {
+	return reportHistoryFileRegexp.MatchString(name)
+}
+
+// parseReportHistoryFileName returns cluster and time of snapshot stored in
+// file with given name
+func parseReportHistoryFileName(name string) (types.ClusterName, time.Time, bool) {
+	matches := reportHistoryFileRegexp.FindStringSubmatch(name)
+	if matches == nil {
+		return "", time.Time{}, false
+	}
+
+	timestamp, err := time.Parse(snapshotTimeFormat, matches[2])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return NormalizeClusterName(types.ClusterName(matches[1])), timestamp, true
+}
+
+// loadReportHistory reads all historical reports from data directory or
+// tar.gz archive. Files that can't be read or that don't contain valid JSON
+// are skipped.
+func loadReportHistory(path string) map[types.ClusterName][]ReportSnapshot {
+	contents := make(map[string][]byte)
+
+	if isArchive(path) {
+		entries, err := readArchive(path)
+		if err != nil {
+			log.Warn().Err(err).Msg("Report history can't be read from archive")
+		}
+		for name, content := range entries {
+			if isReportHistoryFile(name) {
+				contents[name] = content
+			}
+		}
+	} else {
+		files := dataFiles(path)
+		names, err := fs.Glob(files, reportHistoryFileMatch)
+		if err != nil {
+			log.Warn().Err(err).Msg("Report history can't be read")
+		}
+		for _, name := range names {
+			content, err := fs.ReadFile(files, name)
+			if err != nil {
+				log.Warn().Err(err).Str("file", name).Msg("Historical report is skipped")
+				continue
+			}
+			contents[name] = content
+		}
+	}
+
+	history := make(map[types.ClusterName][]ReportSnapshot)
+	for name, content := range contents {
+		cluster, timestamp, ok := parseReportHistoryFileName(name)
+		if !ok {
+			continue
+		}
+		if !json.Valid(content) {
+			log.Warn().Str("file", name).Msg("Historical report is not valid JSON, it is skipped")
+			continue
+		}
+
+		history[cluster] = append(history[cluster], ReportSnapshot{
+			Timestamp: timestamp,
+			Report:    json.RawMessage(content),
+		})
+	}
+
+	for _, snapshots := range history {
+		sort.Slice(snapshots, func(i, j int) bool {
+			return snapshots[i].Timestamp.Before(snapshots[j].Timestamp)
+		})
+	}
+
+	log.Info().Int("clusters", len(history)).Msg("Report history loaded")
+	return history
+}
+
+// swapReportHistory replaces currently loaded historical reports
+func swapReportHistory(newHistory map[types.ClusterName][]ReportSnapshot) {
+	reportHistoryMutex.Lock()
+	defer reportHistoryMutex.Unlock()
+	reportHistory = newHistory
+}
+
+// ReportHistory returns snapshots of report for given cluster, the most
+// recent first. At most limit snapshots are returned when limit is
+// positive. Empty list is returned for cluster without history.
+func (storage MemoryStorage) ReportHistory(clusterName types.ClusterName, limit int) []ReportSnapshot {
+	reportHistoryMutex.RLock()
+	snapshots := reportHistory[NormalizeClusterName(clusterName)]
+	reportHistoryMutex.RUnlock()
+
+	count := len(snapshots)
+	if limit > 0 && limit < count {
+		count = limit
+	}
+
+	history := make([]ReportSnapshot, 0, count)
+	for i := len(snapshots) - 1; i >= len(snapshots)-count; i-- {
+		history = append(history, snapshots[i])
+	}
+	return history
+}
+
+// ReportSnapshotAt returns snapshot of report for given cluster valid at
+// given time, i.e. the snapshot taken exactly at that time or the nearest
+// snapshot taken before. False is returned when the cluster has no snapshot
+// taken at or before given time.
+func (storage MemoryStorage) ReportSnapshotAt(clusterName types.ClusterName, at time.Time) (ReportSnapshot, bool) {
+	reportHistoryMutex.RLock()
+	snapshots := reportHistory[NormalizeClusterName(clusterName)]
+	reportHistoryMutex.RUnlock()
+
+	// index of the first snapshot taken after given time
+	i := sort.Search(len(snapshots), func(i int) bool {
+		return snapshots[i].Timestamp.After(at)
+	})
+	if i == 0 {
+		return ReportSnapshot{}, false
+	}
+	return snapshots[i-1], true
+}
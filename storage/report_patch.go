@@ -0,0 +1,150 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// ErrReportNotFound is returned when the cluster has no report stored
+var ErrReportNotFound = errors.New("report for cluster not found")
+
+// mergePatch applies patch to target using JSON Merge Patch semantics
+// described in RFC 7386
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		// anything else than object replaces the target as a whole
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// MergePatchReport applies JSON Merge Patch to report stored for given
+// cluster and returns the updated report. ErrReportNotFound is returned when
+// the cluster has no report stored.
+func (storage MemoryStorage) MergePatchReport(clusterName types.ClusterName, patch interface{}) (types.ClusterReport, error) {
+	reportsMutex.Lock()
+	defer reportsMutex.Unlock()
+
+	current, found := reports[string(clusterName)]
+	if !found {
+		return "", ErrReportNotFound
+	}
+
+	var parsed interface{}
+	err := json.Unmarshal([]byte(current), &parsed)
+	if err != nil {
+		return "", err
+	}
+
+	patched, err := json.MarshalIndent(mergePatch(parsed, patch), "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	// loaded reports are never modified, so the whole map is replaced
+	newReports := make(map[string]string, len(reports))
+	for cluster, report := range reports {
+		newReports[cluster] = report
+	}
+	newReports[string(clusterName)] = string(patched)
+
+	reports = newReports
+	reportsGeneration++
+	storage.touchReport(clusterName)
+
+	return types.ClusterReport(patched), nil
+}
+
+// DeleteReportForCluster removes report stored for given cluster, so the
+// cluster has no report until deleted reports are restored or data are
+// reloaded. ErrReportNotFound is returned when the cluster has no report
+// stored.
+func (storage MemoryStorage) DeleteReportForCluster(clusterName types.ClusterName) error {
+	reportsMutex.Lock()
+	defer reportsMutex.Unlock()
+
+	current, found := reports[string(clusterName)]
+	if !found {
+		return ErrReportNotFound
+	}
+
+	// loaded reports are never modified, so the whole map is replaced
+	newReports := make(map[string]string, len(reports))
+	for cluster, report := range reports {
+		if cluster != string(clusterName) {
+			newReports[cluster] = report
+		}
+	}
+
+	reports = newReports
+	deletedReports[string(clusterName)] = current
+	reportsGeneration++
+	storage.touchReport(clusterName)
+
+	return nil
+}
+
+// IsReportDeleted checks if report for given cluster has been deleted
+func IsReportDeleted(clusterName types.ClusterName) bool {
+	reportsMutex.RLock()
+	defer reportsMutex.RUnlock()
+
+	_, found := deletedReports[string(NormalizeClusterName(clusterName))]
+	return found
+}
+
+// RestoreDeletedReports puts all deleted reports back
+func (storage MemoryStorage) RestoreDeletedReports() {
+	reportsMutex.Lock()
+	defer reportsMutex.Unlock()
+
+	if len(deletedReports) == 0 {
+		return
+	}
+
+	newReports := make(map[string]string, len(reports)+len(deletedReports))
+	for cluster, report := range reports {
+		newReports[cluster] = report
+	}
+	for cluster, report := range deletedReports {
+		newReports[cluster] = report
+		storage.touchReport(types.ClusterName(cluster))
+	}
+
+	reports = newReports
+	deletedReports = make(map[string]string)
+	reportsGeneration++
+}
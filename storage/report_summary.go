@@ -0,0 +1,69 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// ReportSummary contains compact information about cluster report that is
+// sufficient for overview tables
+type ReportSummary struct {
+	RuleHits         int    `json:"rule_hits"`
+	HighestTotalRisk int    `json:"highest_total_risk"`
+	LastCheckedAt    string `json:"last_checked_at"`
+}
+
+// reportMeta is used to unmarshal metadata from cluster report
+type reportMeta struct {
+	Reports struct {
+		Meta struct {
+			LastCheckedAt string `json:"last_checked_at"`
+		} `json:"meta"`
+	} `json:"reports"`
+}
+
+// SummarizeReport computes summary of given cluster report. Report that
+// can't be parsed is summarized as report without rule hits.
+func SummarizeReport(report types.ClusterReport) ReportSummary {
+	var summary ReportSummary
+
+	hits, err := ParseReportRuleHits(report)
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to parse report rule hits")
+		return summary
+	}
+
+	summary.RuleHits = len(hits)
+	for _, hit := range hits {
+		if hit.TotalRisk > summary.HighestTotalRisk {
+			summary.HighestTotalRisk = hit.TotalRisk
+		}
+	}
+
+	var meta reportMeta
+	err = json.Unmarshal([]byte(report), &meta)
+	if err == nil {
+		summary.LastCheckedAt = meta.Reports.Meta.LastCheckedAt
+	}
+
+	return summary
+}
@@ -0,0 +1,392 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// ruleHitsTransformation modifies list of rule hits stored in report
+type ruleHitsTransformation func(hits []interface{}) []interface{}
+
+// transformReportRuleHits returns copy of given report with rule hits
+// modified by given transformation. Number of rule hits stored in report
+// metadata is updated accordingly.
+func transformReportRuleHits(report types.ClusterReport, transformation ruleHitsTransformation) (types.ClusterReport, error) {
+	var parsed map[string]interface{}
+	err := json.Unmarshal([]byte(report), &parsed)
+	if err != nil {
+		return report, err
+	}
+
+	reports, ok := parsed["reports"].(map[string]interface{})
+	if !ok {
+		return report, errors.New("report does not contain 'reports' object")
+	}
+
+	hits, _ := reports["data"].([]interface{})
+	hits = transformation(hits)
+
+	reports["data"] = hits
+	if meta, ok := reports["meta"].(map[string]interface{}); ok {
+		meta["count"] = len(hits)
+	}
+
+	transformed, err := json.MarshalIndent(parsed, "", "  ")
+	if err != nil {
+		return report, err
+	}
+
+	return types.ClusterReport(transformed), nil
+}
+
+// ruleHitSelector returns selector (rule ID + error key) of rule hit stored
+// in report
+func ruleHitSelector(hit map[string]interface{}) types.RuleSelector {
+	ruleID, _ := hit["rule_id"].(string)
+	errorKey := ""
+	if details, ok := hit["details"].(map[string]interface{}); ok {
+		errorKey, _ = details["error_key"].(string)
+	}
+	return types.RuleSelector(ruleID + "|" + errorKey)
+}
+
+// RuleHitSelectors returns identifiers (rule ID and error key) of all rules
+// hit in given report, in the order stored in the report
+func RuleHitSelectors(report types.ClusterReport) ([]types.RuleSelector, error) {
+	var parsed struct {
+		Reports struct {
+			Data []map[string]interface{} `json:"data"`
+		} `json:"reports"`
+	}
+	err := json.Unmarshal([]byte(report), &parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	selectors := make([]types.RuleSelector, 0, len(parsed.Reports.Data))
+	for _, hit := range parsed.Reports.Data {
+		selectors = append(selectors, ruleHitSelector(hit))
+	}
+	return selectors, nil
+}
+
+// CountReportRuleHits returns number of rule hits stored in given report
+func CountReportRuleHits(report types.ClusterReport) (int, error) {
+	selectors, err := RuleHitSelectors(report)
+	if err != nil {
+		return 0, err
+	}
+	return len(selectors), nil
+}
+
+// ReportDiff contains rule hits present in one report only, identified by
+// rule ID and error key
+type ReportDiff struct {
+	Added   []types.RuleSelector `json:"added"`
+	Removed []types.RuleSelector `json:"removed"`
+}
+
+// DiffReports compares rule hits of two reports. Rule hits present in the
+// second report only are listed as added, rule hits present in the first
+// report only are listed as removed; both lists keep order of rule hits
+// stored in the reports.
+func DiffReports(first, second types.ClusterReport) (ReportDiff, error) {
+	firstSelectors, err := RuleHitSelectors(first)
+	if err != nil {
+		return ReportDiff{}, err
+	}
+	secondSelectors, err := RuleHitSelectors(second)
+	if err != nil {
+		return ReportDiff{}, err
+	}
+
+	return ReportDiff{
+		Added:   selectorsMissingIn(secondSelectors, firstSelectors),
+		Removed: selectorsMissingIn(firstSelectors, secondSelectors),
+	}, nil
+}
+
+// selectorsMissingIn returns selectors that are not part of other selectors
+func selectorsMissingIn(selectors, other []types.RuleSelector) []types.RuleSelector {
+	present := make(map[types.RuleSelector]bool, len(other))
+	for _, selector := range other {
+		present[selector] = true
+	}
+
+	missing := []types.RuleSelector{}
+	for _, selector := range selectors {
+		if !present[selector] {
+			missing = append(missing, selector)
+		}
+	}
+	return missing
+}
+
+// ruleSelectorHash returns stable hash of rule selector
+func ruleSelectorHash(selector types.RuleSelector) uint32 {
+	hash := fnv.New32a()
+	// writes into hash never fail
+	_, _ = hash.Write([]byte(selector))
+	return hash.Sum32()
+}
+
+// SampleReport returns copy of given report with given fraction (0.0-1.0) of
+// its rule hits. The hits are ranked by hash of rule ID and error key and the
+// first ones are selected, so the same subset is returned for each call and
+// the subset for smaller fraction is part of the subset for bigger fraction.
+// Selected hits keep their original order.
+func SampleReport(report types.ClusterReport, fraction float64) (types.ClusterReport, error) {
+	return transformReportRuleHits(report, func(hits []interface{}) []interface{} {
+		count := int(math.Round(fraction * float64(len(hits))))
+
+		ranked := make([]int, len(hits))
+		hashes := make([]uint32, len(hits))
+		for i, item := range hits {
+			ranked[i] = i
+			hit, _ := item.(map[string]interface{})
+			hashes[i] = ruleSelectorHash(ruleHitSelector(hit))
+		}
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return hashes[ranked[i]] < hashes[ranked[j]]
+		})
+
+		selected := make(map[int]bool, count)
+		for _, index := range ranked[:count] {
+			selected[index] = true
+		}
+
+		sampled := make([]interface{}, 0, count)
+		for i, hit := range hits {
+			if selected[i] {
+				sampled = append(sampled, hit)
+			}
+		}
+		return sampled
+	})
+}
+
+// EmptyReport returns copy of given report without any rule hits. Other
+// parts of the report, including its metadata, are kept.
+func EmptyReport(report types.ClusterReport) (types.ClusterReport, error) {
+	return transformReportRuleHits(report, func(hits []interface{}) []interface{} {
+		return []interface{}{}
+	})
+}
+
+// FilterReportByTotalRisk returns copy of given report containing only rule
+// hits with total risk greater than or equal to minRisk. Total risk is taken
+// from given rule content, risk stored in rule hit is used for unknown rules.
+func FilterReportByTotalRisk(report types.ClusterReport, rules []types.RuleWithContent, minRisk int) (types.ClusterReport, error) {
+	totalRiskOf := ruleHitTotalRisk(rules)
+
+	return transformReportRuleHits(report, func(hits []interface{}) []interface{} {
+		filtered := make([]interface{}, 0, len(hits))
+		for _, item := range hits {
+			hit, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			if totalRiskOf(hit) >= minRisk {
+				filtered = append(filtered, hit)
+			}
+		}
+		return filtered
+	})
+}
+
+// ruleHitTotalRisk returns function that computes total risk of rule hit.
+// Total risk is taken from given rule content, risk stored in rule hit is used
+// for unknown rules.
+func ruleHitTotalRisk(rules []types.RuleWithContent) func(hit map[string]interface{}) int {
+	totalRisks := make(map[types.RuleSelector]int, len(rules))
+	for _, rule := range rules {
+		selector := types.RuleSelector(string(rule.Module) + "|" + string(rule.ErrorKey))
+		totalRisks[selector] = rule.TotalRisk
+	}
+
+	return func(hit map[string]interface{}) int {
+		totalRisk, found := totalRisks[ruleHitSelector(hit)]
+		if !found {
+			// JSON numbers are decoded as float64
+			risk, _ := hit["total_risk"].(float64)
+			totalRisk = int(risk)
+		}
+		return totalRisk
+	}
+}
+
+// Supported orderings of rule hits in report
+const (
+	// RuleHitsInFileOrder keeps rule hits in the order stored in report file
+	RuleHitsInFileOrder = "file"
+	// RuleHitsByRuleID sorts rule hits by rule ID and error key
+	RuleHitsByRuleID = "rule_id"
+	// RuleHitsByTotalRisk sorts rule hits by total risk, the highest first
+	RuleHitsByTotalRisk = "total_risk"
+)
+
+// ErrUnknownRuleHitsOrder is returned for unsupported ordering of rule hits
+var ErrUnknownRuleHitsOrder = errors.New("unknown order of rule hits")
+
+// SortReportRuleHits returns copy of given report with rule hits sorted
+// by given key. Sorting is stable and hits with the same total risk are
+// sorted by rule ID, so the order is deterministic. Total risk is taken from
+// given rule content.
+func SortReportRuleHits(report types.ClusterReport, rules []types.RuleWithContent, orderBy string) (types.ClusterReport, error) {
+	var less func(hit1, hit2 map[string]interface{}) bool
+
+	byRuleID := func(hit1, hit2 map[string]interface{}) bool {
+		return ruleHitSelector(hit1) < ruleHitSelector(hit2)
+	}
+
+	switch orderBy {
+	case "", RuleHitsInFileOrder:
+		return report, nil
+	case RuleHitsByRuleID:
+		less = byRuleID
+	case RuleHitsByTotalRisk:
+		totalRiskOf := ruleHitTotalRisk(rules)
+		less = func(hit1, hit2 map[string]interface{}) bool {
+			risk1, risk2 := totalRiskOf(hit1), totalRiskOf(hit2)
+			if risk1 != risk2 {
+				return risk1 > risk2
+			}
+			return byRuleID(hit1, hit2)
+		}
+	default:
+		return report, ErrUnknownRuleHitsOrder
+	}
+
+	return transformReportRuleHits(report, func(hits []interface{}) []interface{} {
+		sort.SliceStable(hits, func(i, j int) bool {
+			hit1, _ := hits[i].(map[string]interface{})
+			hit2, _ := hits[j].(map[string]interface{})
+			return less(hit1, hit2)
+		})
+		return hits
+	})
+}
+
+// RuleHitPredicate decides whether rule hit with given rule content is kept
+// in report
+type RuleHitPredicate func(rule types.RuleWithContent) bool
+
+// FilterReportRuleHits returns copy of given report containing only rule hits
+// satisfying all given predicates. Predicates are evaluated against given rule
+// content, content stored in rule hit is used for unknown rules.
+func FilterReportRuleHits(report types.ClusterReport, rules []types.RuleWithContent, predicates ...RuleHitPredicate) (types.ClusterReport, error) {
+	if len(predicates) == 0 {
+		return report, nil
+	}
+
+	content := ruleContentBySelector(rules)
+
+	return transformReportRuleHits(report, func(hits []interface{}) []interface{} {
+		filtered := make([]interface{}, 0, len(hits))
+		for _, item := range hits {
+			hit, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			if satisfiesAll(ruleContentForHit(content, hit), predicates) {
+				filtered = append(filtered, hit)
+			}
+		}
+		return filtered
+	})
+}
+
+// satisfiesAll checks whether rule satisfies all predicates
+func satisfiesAll(rule types.RuleWithContent, predicates []RuleHitPredicate) bool {
+	for _, predicate := range predicates {
+		if !predicate(rule) {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleContentFromHit returns rule content stored directly in rule hit
+func ruleContentFromHit(hit map[string]interface{}) types.RuleWithContent {
+	ruleID, _ := hit["rule_id"].(string)
+	// JSON numbers are decoded as float64
+	totalRisk, _ := hit["total_risk"].(float64)
+
+	rule := types.RuleWithContent{
+		Module:    types.RuleID(ruleID),
+		TotalRisk: int(totalRisk),
+		Active:    true,
+	}
+
+	tags, _ := hit["tags"].([]interface{})
+	for _, tag := range tags {
+		if tagStr, ok := tag.(string); ok {
+			rule.Tags = append(rule.Tags, tagStr)
+		}
+	}
+	return rule
+}
+
+// ruleContentBySelector returns content of given rules keyed by rule selector
+func ruleContentBySelector(rules []types.RuleWithContent) map[types.RuleSelector]types.RuleWithContent {
+	content := make(map[types.RuleSelector]types.RuleWithContent, len(rules))
+	for _, rule := range rules {
+		content[types.RuleSelector(string(rule.Module)+"|"+string(rule.ErrorKey))] = rule
+	}
+	return content
+}
+
+// ruleContentForHit returns content of rule for given rule hit, content
+// stored in rule hit is used for unknown rules
+func ruleContentForHit(content map[types.RuleSelector]types.RuleWithContent, hit map[string]interface{}) types.RuleWithContent {
+	if rule, found := content[ruleHitSelector(hit)]; found {
+		return rule
+	}
+	return ruleContentFromHit(hit)
+}
+
+// RuleHitsContent returns content of rules for all rule hits in given
+// report, in the order stored in the report. Content stored in rule hit is
+// used for rules not found in given rule content.
+func RuleHitsContent(report types.ClusterReport, rules []types.RuleWithContent) ([]types.RuleWithContent, error) {
+	var parsed struct {
+		Reports struct {
+			Data []map[string]interface{} `json:"data"`
+		} `json:"reports"`
+	}
+	err := json.Unmarshal([]byte(report), &parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	content := ruleContentBySelector(rules)
+
+	hitRules := make([]types.RuleWithContent, 0, len(parsed.Reports.Data))
+	for _, hit := range parsed.Reports.Data {
+		hitRules = append(hitRules, ruleContentForHit(content, hit))
+	}
+	return hitRules, nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// Legacy v1 "flat" schema of report has rule hits stored in top-level
+// "reports" array and report metadata stored directly in top-level object:
+//
+//	{
+//	  "count": 1,
+//	  "last_checked_at": "2020-05-27T14:15:35Z",
+//	  "reports": [ ... ],
+//	  "status": "ok"
+//	}
+const (
+	countKey         = "count"
+	lastCheckedAtKey = "last_checked_at"
+	reportsKey       = "reports"
+	metaKey          = "meta"
+	dataKey          = "data"
+)
+
+// ConvertReportToV1 converts report into legacy v1 "flat" schema
+func ConvertReportToV1(report types.ClusterReport) (types.ClusterReport, error) {
+	var parsed map[string]interface{}
+	err := json.Unmarshal([]byte(report), &parsed)
+	if err != nil {
+		return report, err
+	}
+
+	reports, ok := parsed[reportsKey].(map[string]interface{})
+	if !ok {
+		return report, errors.New("report does not contain 'reports' object")
+	}
+
+	hits, _ := reports[dataKey].([]interface{})
+	if hits == nil {
+		hits = []interface{}{}
+	}
+
+	flat := make(map[string]interface{}, len(parsed)+2)
+	for key, value := range parsed {
+		flat[key] = value
+	}
+	flat[reportsKey] = hits
+	flat[countKey] = len(hits)
+	if meta, ok := reports[metaKey].(map[string]interface{}); ok {
+		flat[lastCheckedAtKey] = meta[lastCheckedAtKey]
+	}
+
+	return marshalReport(report, flat)
+}
+
+// ConvertReportFromV1 converts report stored in legacy v1 "flat" schema into
+// current schema
+func ConvertReportFromV1(report types.ClusterReport) (types.ClusterReport, error) {
+	var flat map[string]interface{}
+	err := json.Unmarshal([]byte(report), &flat)
+	if err != nil {
+		return report, err
+	}
+
+	hits, ok := flat[reportsKey].([]interface{})
+	if !ok {
+		return report, errors.New("report does not contain 'reports' array")
+	}
+
+	meta := map[string]interface{}{
+		countKey:         len(hits),
+		lastCheckedAtKey: flat[lastCheckedAtKey],
+	}
+
+	parsed := make(map[string]interface{}, len(flat))
+	for key, value := range flat {
+		if key == countKey || key == lastCheckedAtKey {
+			continue
+		}
+		parsed[key] = value
+	}
+	parsed[reportsKey] = map[string]interface{}{
+		metaKey: meta,
+		dataKey: hits,
+	}
+
+	return marshalReport(report, parsed)
+}
+
+// marshalReport serializes converted report, original report is returned
+// together with error when it is not possible
+func marshalReport(original types.ClusterReport, converted interface{}) (types.ClusterReport, error) {
+	serialized, err := json.MarshalIndent(converted, "", "  ")
+	if err != nil {
+		return original, err
+	}
+	return types.ClusterReport(serialized), nil
+}
@@ -15,12 +15,80 @@
 package storage
 
 import (
+	"sort"
+
+	"github.com/rs/zerolog/log"
+
 	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
 )
 
-// GetRuleWithContent returns rule with content for provided ruleID and ruleErrorKey
+// GetRuleWithContent returns rule with content for provided ruleID and
+// ruleErrorKey. Loaded rule content is used when available, otherwise the
+// content is taken from reports.
 func (storage MemoryStorage) GetRuleWithContent(ruleID types.RuleID, ruleErrorKey types.ErrorKey) (*types.RuleWithContent, error) {
-	var result types.RuleWithContent
+	selector := types.RuleSelector(string(ruleID) + "|" + string(ruleErrorKey))
+	if rule, found := loadedRuleContent()[selector]; found {
+		return &rule, nil
+	}
+
+	rules, err := storage.ListOfRulesWithContent()
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		if rules[i].Module == ruleID && rules[i].ErrorKey == ruleErrorKey {
+			return &rules[i], nil
+		}
+	}
+
+	return nil, &types.ItemNotFoundError{ItemID: selector}
+}
+
+// ListOfRulesWithContent returns all rules that are hit in at least one
+// loaded report. Rule content is taken from loaded rule content when
+// available, otherwise from the reports.
+func (storage MemoryStorage) ListOfRulesWithContent() ([]types.RuleWithContent, error) {
+	rules := make(map[types.RuleSelector]types.RuleWithContent)
+	content := loadedRuleContent()
+
+	for cluster, report := range loadedReports() {
+		hits, err := ParseReportRuleHits(types.ClusterReport(report))
+		if err != nil {
+			log.Error().Err(err).Str("cluster", cluster).Msg("Unable to parse report")
+			continue
+		}
+
+		for _, hit := range hits {
+			selector := types.RuleSelector(string(hit.RuleID) + "|" + string(hit.Details.ErrorKey))
+			if rule, found := content[selector]; found {
+				rules[selector] = rule
+				continue
+			}
+			rules[selector] = types.RuleWithContent{
+				Module:      hit.RuleID,
+				ErrorKey:    hit.Details.ErrorKey,
+				Description: hit.Description,
+				Reason:      hit.Reason,
+				Resolution:  hit.Resolution,
+				TotalRisk:   hit.TotalRisk,
+				Active:      true,
+				Tags:        hit.Tags,
+			}
+		}
+	}
+
+	result := make([]types.RuleWithContent, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, rule)
+	}
+
+	// map iteration order is random
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Module != result[j].Module {
+			return result[i].Module < result[j].Module
+		}
+		return result[i].ErrorKey < result[j].ErrorKey
+	})
 
-	return &result, nil
+	return result, nil
 }
@@ -0,0 +1,316 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-yaml/yaml"
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// Rule content can be stored in directory with this name in data directory.
+// The layout is the same as used by insights-content-service:
+//
+//	content/config.yaml                       names of impacts
+//	content/**/{plugin}/plugin.yaml           name and Python module of rule
+//	content/**/{plugin}/*.md                  summary, reason, resolution, more info
+//	content/**/{plugin}/{error key}/metadata.yaml
+//	content/**/{plugin}/{error key}/*.md      generic text and overrides of
+//	                                          plugin texts
+const ruleContentDirName = "content"
+
+const (
+	pluginFileName   = "plugin.yaml"
+	metadataFileName = "metadata.yaml"
+	contentConfig    = "config.yaml"
+
+	// suffix of Python module that is not part of rule ID
+	ruleModuleSuffix = ".report"
+)
+
+// publish_date in content metadata uses this format
+const publishDateFormat = "2006-01-02 15:04:05"
+
+// ruleContent maps rule selector to rule content
+type ruleContent map[types.RuleSelector]types.RuleWithContent
+
+var (
+	loadedContent ruleContent = ruleContent{}
+	contentMutex  sync.RWMutex
+)
+
+// contentPluginFile represents plugin.yaml file
+type contentPluginFile struct {
+	Name         string `yaml:"name"`
+	PythonModule string `yaml:"python_module"`
+}
+
+// contentMetadataFile represents metadata.yaml file of one error key
+type contentMetadataFile struct {
+	Condition   string      `yaml:"condition"`
+	Description string      `yaml:"description"`
+	Impact      interface{} `yaml:"impact"`
+	Likelihood  int         `yaml:"likelihood"`
+	PublishDate string      `yaml:"publish_date"`
+	Status      string      `yaml:"status"`
+	Tags        []string    `yaml:"tags"`
+}
+
+// contentConfigFile represents config.yaml file with impact values
+type contentConfigFile struct {
+	Impact map[string]int `yaml:"impact"`
+}
+
+// contentTexts contains texts stored in Markdown files of plugin or error key
+type contentTexts struct {
+	Summary    string
+	Reason     string
+	Resolution string
+	MoreInfo   string
+	Generic    string
+}
+
+// loadRuleContent reads rule content from content directory stored in data
+// directory. Empty content is used when the directory does not exist or when
+// data are read from tar.gz archive.
+func loadRuleContent(dataPath string) (ruleContent, error) {
+	if isArchive(dataPath) {
+		return ruleContent{}, nil
+	}
+
+	files := dataFiles(dataPath)
+	_, err := fs.Stat(files, ruleContentDirName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ruleContent{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	impacts, err := readContentConfig(files)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := readContentDir(files, ruleContentDirName, impacts)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Int("rules", len(content)).Msg("Rule content loaded")
+	return content, nil
+}
+
+// readContentDir reads content of all plugins stored in given directory
+// tree
+func readContentDir(files fs.FS, dir string, impacts map[string]int) (ruleContent, error) {
+	content := ruleContent{}
+	err := fs.WalkDir(files, dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || entry.Name() != pluginFileName {
+			return nil
+		}
+		return readContentPlugin(files, path.Dir(name), impacts, content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// readContentConfig reads mapping between impact names and impact values,
+// empty mapping is returned when the config file does not exist
+func readContentConfig(files fs.FS) (map[string]int, error) {
+	var config contentConfigFile
+
+	err := readYAMLFile(files, path.Join(ruleContentDirName, contentConfig), &config)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]int{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config.Impact, nil
+}
+
+// readContentPlugin reads content of all error keys of plugin stored in
+// given directory
+func readContentPlugin(files fs.FS, dir string, impacts map[string]int, content ruleContent) error {
+	var plugin contentPluginFile
+	err := readYAMLFile(files, path.Join(dir, pluginFileName), &plugin)
+	if err != nil {
+		return err
+	}
+	if plugin.PythonModule == "" {
+		return fmt.Errorf("%s: python_module is not specified", path.Join(dir, pluginFileName))
+	}
+	module := types.RuleID(strings.TrimSuffix(plugin.PythonModule, ruleModuleSuffix))
+
+	pluginTexts, err := readContentTexts(files, dir, contentTexts{})
+	if err != nil {
+		return err
+	}
+
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		errorKeyDir := path.Join(dir, entry.Name())
+
+		var metadata contentMetadataFile
+		err := readYAMLFile(files, path.Join(errorKeyDir, metadataFileName), &metadata)
+		if errors.Is(err, fs.ErrNotExist) {
+			// not an error key directory
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		impact, err := contentImpact(metadata.Impact, impacts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path.Join(errorKeyDir, metadataFileName), err)
+		}
+
+		// texts of error key override texts of plugin
+		texts, err := readContentTexts(files, errorKeyDir, pluginTexts)
+		if err != nil {
+			return err
+		}
+
+		errorKey := types.ErrorKey(entry.Name())
+		selector := types.RuleSelector(string(module) + "|" + string(errorKey))
+		content[selector] = types.RuleWithContent{
+			Module:      module,
+			Name:        plugin.Name,
+			Summary:     texts.Summary,
+			Reason:      texts.Reason,
+			Resolution:  texts.Resolution,
+			MoreInfo:    texts.MoreInfo,
+			ErrorKey:    errorKey,
+			Condition:   metadata.Condition,
+			Description: metadata.Description,
+			TotalRisk:   (impact + metadata.Likelihood) / 2,
+			PublishDate: parsePublishDate(metadata.PublishDate),
+			Active:      metadata.Status == "active",
+			Generic:     texts.Generic,
+			Tags:        metadata.Tags,
+		}
+	}
+
+	return nil
+}
+
+// readContentTexts reads Markdown files stored in given directory, texts
+// from defaults are used for files that don't exist
+func readContentTexts(files fs.FS, dir string, defaults contentTexts) (contentTexts, error) {
+	texts := defaults
+
+	targets := map[string]*string{
+		"summary.md":    &texts.Summary,
+		"reason.md":     &texts.Reason,
+		"resolution.md": &texts.Resolution,
+		"more_info.md":  &texts.MoreInfo,
+		"generic.md":    &texts.Generic,
+	}
+
+	for fileName, target := range targets {
+		text, err := fs.ReadFile(files, path.Join(dir, fileName))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return texts, err
+		}
+		*target = strings.TrimSpace(string(text))
+	}
+
+	return texts, nil
+}
+
+// contentImpact returns impact value, impact can be specified directly or
+// by its name defined in content config
+func contentImpact(impact interface{}, impacts map[string]int) (int, error) {
+	switch value := impact.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return value, nil
+	case string:
+		if number, found := impacts[value]; found {
+			return number, nil
+		}
+		return 0, fmt.Errorf("unknown impact %q", value)
+	default:
+		return 0, fmt.Errorf("improper impact %v", value)
+	}
+}
+
+// parsePublishDate parses publish date in format used by content metadata or
+// in RFC 3339 format, zero time is returned for improper date
+func parsePublishDate(date string) time.Time {
+	for _, layout := range []string{publishDateFormat, time.RFC3339} {
+		if parsed, err := time.Parse(layout, date); err == nil {
+			return parsed
+		}
+	}
+	return time.Time{}
+}
+
+// readYAMLFile reads and parses YAML file
+func readYAMLFile(files fs.FS, name string, out interface{}) error {
+	data, err := fs.ReadFile(files, name)
+	if err != nil {
+		return err
+	}
+
+	err = yaml.Unmarshal(data, out)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// loadedRuleContent returns currently loaded rule content
+func loadedRuleContent() ruleContent {
+	contentMutex.RLock()
+	defer contentMutex.RUnlock()
+	return loadedContent
+}
+
+// swapRuleContent replaces loaded rule content
+func swapRuleContent(newContent ruleContent) {
+	contentMutex.Lock()
+	defer contentMutex.Unlock()
+	loadedContent = newContent
+}
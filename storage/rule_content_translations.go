@@ -0,0 +1,175 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// DefaultContentLanguage is language of rule content stored in content
+// directory and of rule content taken from reports
+const DefaultContentLanguage = "en"
+
+// Translations of rule content can be stored in directories named
+// content_{language} in data directory, for example content_de or
+// content_pt-br. The layout is the same as layout of content directory.
+var contentTranslationDirRegexp = regexp.MustCompile(`^` + ruleContentDirName + `_([a-zA-Z]{2,3}(-[a-zA-Z0-9]{1,8})*)$`)
+
+// translations of rule content keyed by language, replaced as a whole on
+// reload
+var (
+	loadedTranslations      = map[string]ruleContent{}
+	loadedTranslationsMutex sync.RWMutex
+)
+
+// loadRuleContentTranslations reads all translations of rule content stored
+// in data directory. Impacts defined in config of content directory are used.
+// No translations are read from tar.gz archive.
+func loadRuleContentTranslations(dataPath string) (map[string]ruleContent, error) {
+	translations := map[string]ruleContent{}
+	if isArchive(dataPath) {
+		return translations, nil
+	}
+
+	files := dataFiles(dataPath)
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	impacts, err := readContentConfig(files)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		matches := contentTranslationDirRegexp.FindStringSubmatch(entry.Name())
+		if !entry.IsDir() || matches == nil {
+			continue
+		}
+
+		content, err := readContentDir(files, entry.Name(), impacts)
+		if err != nil {
+			return nil, err
+		}
+		translations[strings.ToLower(matches[1])] = content
+	}
+
+	log.Info().Int("languages", len(translations)).Msg("Rule content translations loaded")
+	return translations, nil
+}
+
+// loadedRuleContentTranslations returns currently loaded translations of
+// rule content
+func loadedRuleContentTranslations() map[string]ruleContent {
+	loadedTranslationsMutex.RLock()
+	defer loadedTranslationsMutex.RUnlock()
+	return loadedTranslations
+}
+
+// swapRuleContentTranslations replaces loaded translations of rule content
+func swapRuleContentTranslations(newTranslations map[string]ruleContent) {
+	loadedTranslationsMutex.Lock()
+	defer loadedTranslationsMutex.Unlock()
+	loadedTranslations = newTranslations
+}
+
+// RuleContentLanguages returns languages (in lower case) with loaded
+// translation of rule content, the default language is not included
+func (storage MemoryStorage) RuleContentLanguages() []string {
+	translations := loadedRuleContentTranslations()
+
+	languages := make([]string, 0, len(translations))
+	for language := range translations {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// LocalizeRules returns copy of given rules with texts translated into given
+// language. Texts that are not translated are kept in the default language.
+func (storage MemoryStorage) LocalizeRules(rules []types.RuleWithContent, language string) []types.RuleWithContent {
+	translation := loadedRuleContentTranslations()[strings.ToLower(language)]
+
+	localized := make([]types.RuleWithContent, len(rules))
+	for i, rule := range rules {
+		localized[i] = rule
+		translated, found := translation[types.RuleSelector(string(rule.Module)+"|"+string(rule.ErrorKey))]
+		if !found {
+			continue
+		}
+
+		targets := map[*string]string{
+			&localized[i].Name:        translated.Name,
+			&localized[i].Summary:     translated.Summary,
+			&localized[i].Reason:      translated.Reason,
+			&localized[i].Resolution:  translated.Resolution,
+			&localized[i].MoreInfo:    translated.MoreInfo,
+			&localized[i].Description: translated.Description,
+			&localized[i].Generic:     translated.Generic,
+		}
+		for target, text := range targets {
+			if text != "" {
+				*target = text
+			}
+		}
+	}
+	return localized
+}
+
+// RuleContent returns content of all error keys of given rule. Loaded rule
+// content is used when available, otherwise the content is taken from
+// reports.
+func (storage MemoryStorage) RuleContent(ruleID types.RuleID) ([]types.RuleWithContent, error) {
+	var rules []types.RuleWithContent
+	for _, rule := range loadedRuleContent() {
+		if rule.Module == ruleID {
+			rules = append(rules, rule)
+		}
+	}
+
+	if len(rules) == 0 {
+		hitRules, err := storage.ListOfRulesWithContent()
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range hitRules {
+			if rule.Module == ruleID {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil, &types.ItemNotFoundError{ItemID: ruleID}
+	}
+
+	// map iteration order is random
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ErrorKey < rules[j].ErrorKey
+	})
+	return rules, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// RuleFrequency represents number of clusters hit by one rule
+type RuleFrequency struct {
+	RuleID   types.RuleID   `json:"rule_id"`
+	ErrorKey types.ErrorKey `json:"error_key"`
+	Clusters int            `json:"clusters"`
+}
+
+// ruleFrequencies contains frequencies of all rules computed from given
+// generation of reports
+type ruleFrequencies struct {
+	generation  uint64
+	frequencies []RuleFrequency
+}
+
+// frequencies are computed by scanning all reports, so they are cached until
+// reports are replaced or patched
+var (
+	cachedRuleFrequencies      *ruleFrequencies
+	cachedRuleFrequenciesMutex sync.Mutex
+)
+
+// RuleHitFrequency returns number of loaded clusters hit by each rule, the
+// most frequent rules first. At most limit rules are returned, all rules when
+// the limit is not positive.
+func (storage MemoryStorage) RuleHitFrequency(limit int) []RuleFrequency {
+	frequencies := computeRuleFrequencies().frequencies
+	if limit > 0 && len(frequencies) > limit {
+		frequencies = frequencies[:limit]
+	}
+	return frequencies
+}
+
+// computeRuleFrequencies returns frequencies of all rules, they are computed
+// when reports have been replaced since the last call
+func computeRuleFrequencies() *ruleFrequencies {
+	generation := loadedReportsGeneration()
+
+	cachedRuleFrequenciesMutex.Lock()
+	defer cachedRuleFrequenciesMutex.Unlock()
+
+	if cachedRuleFrequencies != nil && cachedRuleFrequencies.generation == generation {
+		return cachedRuleFrequencies
+	}
+
+	counts := make(map[types.RuleSelector]*RuleFrequency)
+	for cluster, report := range loadedReports() {
+		hits, err := ParseReportRuleHits(types.ClusterReport(report))
+		if err != nil {
+			log.Error().Err(err).Str("cluster", cluster).Msg("Unable to parse report")
+			continue
+		}
+
+		// one rule might be hit more times in the same report
+		seen := make(map[types.RuleSelector]bool)
+		for _, hit := range hits {
+			selector := types.RuleSelector(string(hit.RuleID) + "|" + string(hit.Details.ErrorKey))
+			if seen[selector] {
+				continue
+			}
+			seen[selector] = true
+
+			frequency, found := counts[selector]
+			if !found {
+				frequency = &RuleFrequency{RuleID: hit.RuleID, ErrorKey: hit.Details.ErrorKey}
+				counts[selector] = frequency
+			}
+			frequency.Clusters++
+		}
+	}
+
+	frequencies := make([]RuleFrequency, 0, len(counts))
+	for _, frequency := range counts {
+		frequencies = append(frequencies, *frequency)
+	}
+
+	// map iteration order is random, so rules with the same frequency are
+	// sorted by rule ID and error key
+	sort.Slice(frequencies, func(i, j int) bool {
+		if frequencies[i].Clusters != frequencies[j].Clusters {
+			return frequencies[i].Clusters > frequencies[j].Clusters
+		}
+		if frequencies[i].RuleID != frequencies[j].RuleID {
+			return frequencies[i].RuleID < frequencies[j].RuleID
+		}
+		return frequencies[i].ErrorKey < frequencies[j].ErrorKey
+	})
+
+	cachedRuleFrequencies = &ruleFrequencies{
+		generation:  generation,
+		frequencies: frequencies,
+	}
+	return cachedRuleFrequencies
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 Red Hat, Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"encoding/json"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// ReportRuleHitDetails represents details of rule hit stored in report
+type ReportRuleHitDetails struct {
+	ErrorKey types.ErrorKey `json:"error_key"`
+}
+
+// ReportRuleHit represents one rule hit stored in cluster report
+type ReportRuleHit struct {
+	RuleID       types.RuleID         `json:"rule_id"`
+	Details      ReportRuleHitDetails `json:"details"`
+	CreatedAt    string               `json:"created_at"`
+	Description  string               `json:"description"`
+	Reason       string               `json:"reason"`
+	Resolution   string               `json:"resolution"`
+	TotalRisk    int                  `json:"total_risk"`
+	RiskOfChange int                  `json:"risk_of_change"`
+	Tags         []string             `json:"tags"`
+}
+
+// reportWithRuleHits is used to unmarshal rule hits from cluster report
+type reportWithRuleHits struct {
+	Reports struct {
+		Data []ReportRuleHit `json:"data"`
+	} `json:"reports"`
+}
+
+// ParseReportRuleHits returns all rule hits stored in given cluster report
+func ParseReportRuleHits(report types.ClusterReport) ([]ReportRuleHit, error) {
+	var parsed reportWithRuleHits
+
+	err := json.Unmarshal([]byte(report), &parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.Reports.Data, nil
+}
+
+// CountRuleHits returns number of rule hits in given reports of clusters.
+// Clusters with empty report (or with report that can't be parsed) are
+// treated as clusters with zero hits.
+func CountRuleHits(reports map[types.ClusterName]types.ClusterReport) map[types.ClusterName]int {
+	counts := make(map[types.ClusterName]int, len(reports))
+
+	for cluster, report := range reports {
+		counts[cluster] = 0
+		if report == "" {
+			continue
+		}
+
+		hits, err := ParseReportRuleHits(report)
+		if err != nil {
+			continue
+		}
+		counts[cluster] = len(hits)
+	}
+
+	return counts
+}
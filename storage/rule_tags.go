@@ -0,0 +1,102 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// ruleTagIndex maps tags to rules bearing them, lowercased tags are stored
+// in separate map for case-insensitive lookup
+type ruleTagIndex struct {
+	generation  uint64
+	exact       map[string][]types.RuleWithContent
+	insensitive map[string][]types.RuleWithContent
+}
+
+// tag index is computed from rule content of all reports, so it is cached
+// until reports are replaced
+var (
+	cachedRuleTagIndex      *ruleTagIndex
+	cachedRuleTagIndexMutex sync.Mutex
+)
+
+// RulesWithTag returns all rules bearing given tag. Tags can be compared
+// case-insensitively.
+func (storage MemoryStorage) RulesWithTag(tag string, ignoreCase bool) ([]types.RuleWithContent, error) {
+	index, err := storage.ruleTagIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []types.RuleWithContent
+	if ignoreCase {
+		rules = index.insensitive[strings.ToLower(tag)]
+	} else {
+		rules = index.exact[tag]
+	}
+
+	if rules == nil {
+		return []types.RuleWithContent{}, nil
+	}
+	return rules, nil
+}
+
+// ruleTagIndex returns index of rules by their tags, the index is computed
+// when reports have been replaced since the last call
+func (storage MemoryStorage) ruleTagIndex() (*ruleTagIndex, error) {
+	generation := loadedReportsGeneration()
+
+	cachedRuleTagIndexMutex.Lock()
+	defer cachedRuleTagIndexMutex.Unlock()
+
+	if cachedRuleTagIndex != nil && cachedRuleTagIndex.generation == generation {
+		return cachedRuleTagIndex, nil
+	}
+
+	rules, err := storage.ListOfRulesWithContent()
+	if err != nil {
+		return nil, err
+	}
+
+	index := &ruleTagIndex{
+		generation:  generation,
+		exact:       make(map[string][]types.RuleWithContent),
+		insensitive: make(map[string][]types.RuleWithContent),
+	}
+
+	// rules are sorted already, so lists in index are sorted as well
+	for _, rule := range rules {
+		seen := make(map[string]bool)
+		for _, tag := range rule.Tags {
+			index.exact[tag] = append(index.exact[tag], rule)
+
+			// the same rule might have tags differing in case only
+			lowercased := strings.ToLower(tag)
+			if !seen[lowercased] {
+				seen[lowercased] = true
+				index.insensitive[lowercased] = append(index.insensitive[lowercased], rule)
+			}
+		}
+	}
+
+	cachedRuleTagIndex = index
+	return index, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// rule hits get created_at timestamps up to this number of hours before
+// the time the report has been checked
+const ruleTimestampsMaxAgeInHours = 30 * 24
+
+// ruleHitAge returns how long before the check given rule has been hit for
+// the first time. The age is derived from rule selector, so it is the same
+// for given rule in all reports.
+func ruleHitAge(selector types.RuleSelector) time.Duration {
+	hours := ruleSelectorHash(selector)%ruleTimestampsMaxAgeInHours + 1
+	return time.Duration(hours) * time.Hour
+}
+
+// AddRuleTimestamps returns copy of given report with created_at timestamp
+// set for each rule hit. Timestamps are deterministic, computed from time
+// the report has been checked minus age derived from rule selector. Report
+// without proper last_checked_at time is returned as is.
+func AddRuleTimestamps(report types.ClusterReport) (types.ClusterReport, error) {
+	var meta reportMeta
+	err := json.Unmarshal([]byte(report), &meta)
+	if err != nil {
+		return report, err
+	}
+
+	checkedAt, err := time.Parse(time.RFC3339, meta.Reports.Meta.LastCheckedAt)
+	if err != nil {
+		return report, nil
+	}
+
+	return transformReportRuleHits(report, func(hits []interface{}) []interface{} {
+		for _, item := range hits {
+			hit, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			createdAt := checkedAt.Add(-ruleHitAge(ruleHitSelector(hit)))
+			hit["created_at"] = createdAt.UTC().Format(time.RFC3339)
+		}
+		return hits
+	})
+}
+
+// addRuleTimestampsWhenEnabled adds created_at timestamps to rule hits in
+// given report when enabled by configuration
+func (storage MemoryStorage) addRuleTimestampsWhenEnabled(report types.ClusterReport) (types.ClusterReport, error) {
+	if report == "" || !storage.ruleTimestamps {
+		return report, nil
+	}
+	return AddRuleTimestamps(report)
+}
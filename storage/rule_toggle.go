@@ -15,8 +15,12 @@
 package storage
 
 import (
-	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+	"errors"
+	"sort"
+	"sync"
 	"time"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
 )
 
 // RuleToggle is a type for user's vote
@@ -29,6 +33,10 @@ const (
 	RuleToggleEnable RuleToggle = 0
 )
 
+// ErrRuleToggleNotFound is returned when the rule has never been toggled for
+// the cluster
+var ErrRuleToggleNotFound = errors.New("rule has not been toggled for this cluster")
+
 // ClusterRuleToggle represents a record from rule_cluster_toggle
 type ClusterRuleToggle struct {
 	ClusterID  types.ClusterName
@@ -40,21 +48,93 @@ type ClusterRuleToggle struct {
 	UpdatedAt  time.Time
 }
 
+// rule toggles are stored in memory only, key is cluster name and rule ID
+var (
+	clusterRuleToggles = make(map[types.ClusterName]map[types.RuleID]ClusterRuleToggle)
+	ruleTogglesMutex   sync.RWMutex
+)
+
 // ToggleRuleForCluster toggles rule for specified cluster
 func (storage MemoryStorage) ToggleRuleForCluster(
 	clusterID types.ClusterName, ruleID types.RuleID, userID types.UserID, ruleToggle RuleToggle,
 ) error {
+	ruleTogglesMutex.Lock()
+	defer ruleTogglesMutex.Unlock()
+
+	toggles, found := clusterRuleToggles[clusterID]
+	if !found {
+		toggles = make(map[types.RuleID]ClusterRuleToggle)
+		clusterRuleToggles[clusterID] = toggles
+	}
+
+	currentTime := storage.now()
+	toggle := toggles[ruleID]
+	toggle.ClusterID = clusterID
+	toggle.RuleID = ruleID
+	toggle.UserID = userID
+	toggle.Disabled = ruleToggle
+	toggle.UpdatedAt = currentTime
+	if ruleToggle == RuleToggleDisable {
+		toggle.DisabledAt = currentTime
+	} else {
+		toggle.EnabledAt = currentTime
+	}
+	toggles[ruleID] = toggle
+	reportsChanged()
 
 	return nil
 }
 
+// disabledRulesForCluster returns set of rules currently disabled for
+// specified cluster
+func disabledRulesForCluster(clusterID types.ClusterName) map[types.RuleID]bool {
+	ruleTogglesMutex.RLock()
+	defer ruleTogglesMutex.RUnlock()
+
+	disabled := make(map[types.RuleID]bool)
+	for ruleID, toggle := range clusterRuleToggles[clusterID] {
+		if toggle.Disabled == RuleToggleDisable {
+			disabled[ruleID] = true
+		}
+	}
+	return disabled
+}
+
 // ListDisabledRulesForCluster retrieves disabled rules for specified cluster
 func (storage MemoryStorage) ListDisabledRulesForCluster(
 	clusterID types.ClusterName, userID types.UserID,
 ) ([]types.DisabledRuleResponse, error) {
+	rulesContent, err := storage.ListOfRulesWithContent()
+	if err != nil {
+		return nil, err
+	}
+
+	ruleTogglesMutex.RLock()
+	defer ruleTogglesMutex.RUnlock()
 
 	rules := make([]types.DisabledRuleResponse, 0)
+	for ruleID, toggle := range clusterRuleToggles[clusterID] {
+		if toggle.Disabled != RuleToggleDisable {
+			continue
+		}
+
+		rule := types.DisabledRuleResponse{
+			RuleModule: string(ruleID),
+			DisabledAt: toggle.DisabledAt.UTC().Format(time.RFC3339),
+		}
+		for _, content := range rulesContent {
+			if content.Module == ruleID {
+				rule.Description = content.Description
+				rule.Generic = content.Generic
+				break
+			}
+		}
+		rules = append(rules, rule)
+	}
 
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].RuleModule < rules[j].RuleModule
+	})
 	return rules, nil
 }
 
@@ -62,7 +142,13 @@ func (storage MemoryStorage) ListDisabledRulesForCluster(
 func (storage MemoryStorage) GetFromClusterRuleToggle(
 	clusterID types.ClusterName, ruleID types.RuleID, userID types.UserID,
 ) (*ClusterRuleToggle, error) {
-	var disabledRule ClusterRuleToggle
+	ruleTogglesMutex.RLock()
+	defer ruleTogglesMutex.RUnlock()
+
+	disabledRule, found := clusterRuleToggles[clusterID][ruleID]
+	if !found {
+		return nil, ErrRuleToggleNotFound
+	}
 
 	return &disabledRule, nil
 }
@@ -71,5 +157,35 @@ func (storage MemoryStorage) GetFromClusterRuleToggle(
 func (storage MemoryStorage) DeleteFromRuleClusterToggle(
 	clusterID types.ClusterName, ruleID types.RuleID, userID types.UserID,
 ) error {
+	ruleTogglesMutex.Lock()
+	defer ruleTogglesMutex.Unlock()
+
+	delete(clusterRuleToggles[clusterID], ruleID)
+	reportsChanged()
 	return nil
 }
+
+// filterDisabledRules removes hits of rules disabled for given cluster from
+// its report
+func filterDisabledRules(clusterName types.ClusterName, report types.ClusterReport) (types.ClusterReport, error) {
+	disabled := disabledRulesForCluster(clusterName)
+	if len(disabled) == 0 || report == "" {
+		return report, nil
+	}
+
+	return transformReportRuleHits(report, func(hits []interface{}) []interface{} {
+		filtered := make([]interface{}, 0, len(hits))
+		for _, item := range hits {
+			hit, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			ruleID, _ := hit["rule_id"].(string)
+			if !disabled[types.RuleID(ruleID)] {
+				filtered = append(filtered, hit)
+			}
+		}
+		return filtered
+	})
+}
@@ -0,0 +1,75 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// clusters that simulate report that is not ready yet
+// please note that these clusters have special name:
+// "aaaaaaaa-aaaa-aaaa-aaaa-{index}"
+//
+// Mnemotechnic: a - accepted
+const slowClusterIDPrefix = "aaaaaaaa-aaaa-aaaa-aaaa-"
+
+// time when report for "slow cluster" (or for cluster in batch request) has
+// been requested for the first time
+var (
+	slowClustersFirstSeen = make(map[types.ClusterName]time.Time)
+	slowClustersMutex     sync.Mutex
+)
+
+// IsSlowCluster checks if given cluster simulates report that is not ready
+// immediately
+func IsSlowCluster(clusterName types.ClusterName) bool {
+	return strings.HasPrefix(string(clusterName), slowClusterIDPrefix)
+}
+
+// ReportReadyIn returns time remaining until report for "slow cluster" (or
+// cluster in batch request) becomes ready. The delay is measured from the first request for given
+// cluster. Zero is returned when the report is ready already.
+func (storage MemoryStorage) ReportReadyIn(clusterName types.ClusterName, delay time.Duration) time.Duration {
+	slowClustersMutex.Lock()
+	defer slowClustersMutex.Unlock()
+
+	currentTime := storage.now()
+	firstSeen, found := slowClustersFirstSeen[clusterName]
+	if !found {
+		firstSeen = currentTime
+		slowClustersFirstSeen[clusterName] = firstSeen
+	}
+
+	remaining := firstSeen.Add(delay).Sub(currentTime)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// ResetSlowClusters forgets all "slow clusters" seen so far, so their reports
+// are not ready again
+func (storage MemoryStorage) ResetSlowClusters() {
+	slowClustersMutex.Lock()
+	defer slowClustersMutex.Unlock()
+
+	slowClustersFirstSeen = make(map[types.ClusterName]time.Time)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"sort"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// StorageStats represents summary of data currently held in memory storage
+type StorageStats struct {
+	ReportsCount     int                 `json:"reports_count"`
+	Clusters         []types.ClusterName `json:"clusters"`
+	Organizations    []types.OrgID       `json:"organizations"`
+	ChangingClusters []types.ClusterName `json:"changing_clusters"`
+	ReportTemplates  []string            `json:"report_templates"`
+}
+
+// Stats returns summary of data currently held in memory storage
+func (storage MemoryStorage) Stats() StorageStats {
+	reports := loadedReports()
+	clusters := make([]types.ClusterName, 0, len(reports))
+	for cluster := range reports {
+		clusters = append(clusters, types.ClusterName(cluster))
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i] < clusters[j] })
+
+	changing := make([]types.ClusterName, 0, len(changingClusters))
+	for cluster := range changingClusters {
+		changing = append(changing, types.ClusterName(cluster))
+	}
+	sort.Slice(changing, func(i, j int) bool { return changing[i] < changing[j] })
+
+	reportTemplates := loadedReportTemplates()
+	templates := make([]string, 0, len(reportTemplates))
+	for prefix := range reportTemplates {
+		templates = append(templates, prefix)
+	}
+	sort.Strings(templates)
+
+	// list of organizations is static and never fails
+	orgs, _ := storage.ListOfOrgs()
+
+	return StorageStats{
+		ReportsCount:     len(reports),
+		Clusters:         clusters,
+		Organizations:    orgs,
+		ChangingClusters: changing,
+		ReportTemplates:  templates,
+	}
+}
@@ -21,15 +21,21 @@ See the License for the specific language governing permissions and
 package storage
 
 import (
-	"errors"
-	"io/ioutil"
+	"hash/fnv"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/RedHatInsights/insights-results-aggregator-mock/clock"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/data"
 	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
 )
 
@@ -39,7 +45,11 @@ type Storage interface {
 	Close() error
 	ListOfOrgs() ([]types.OrgID, error)
 	ListOfClustersForOrg(orgID types.OrgID) ([]types.ClusterName, error)
+	IsKnownOrganization(orgID types.OrgID) bool
+	KnownOrganizations() []types.OrgID
 	ReadReportForCluster(clusterName types.ClusterName) (types.ClusterReport, error)
+	ReadStableReportForCluster(clusterName types.ClusterName) (types.ClusterReport, error)
+	ResolveClusterName(nameOrUUID string) (types.ClusterName, error)
 	ReadReportForOrganizationAndCluster(orgID types.OrgID, clusterName types.ClusterName) (types.ClusterReport, error)
 	ReadReportForClusterByClusterName(clusterName types.ClusterName) (types.ClusterReport, types.Timestamp, error)
 	ReportsCount() (int, error)
@@ -85,45 +95,171 @@ type Storage interface {
 	) error
 	GetRuleByID(ruleID types.RuleID) (*types.Rule, error)
 	GetOrgIDByClusterID(cluster types.ClusterName) (types.OrgID, error)
+	OwnersOfCluster(cluster types.ClusterName) []types.OrgID
 	GetUserFeedbackOnRules(
 		clusterID types.ClusterName,
 		rulesContent []types.RuleContentResponse,
 		userID types.UserID,
 	) (map[types.RuleID]types.UserVote, error)
 	GetRuleWithContent(ruleID types.RuleID, ruleErrorKey types.ErrorKey) (*types.RuleWithContent, error)
+	ListOfRulesWithContent() ([]types.RuleWithContent, error)
+	RuleContent(ruleID types.RuleID) ([]types.RuleWithContent, error)
+	RuleContentLanguages() []string
+	LocalizeRules(rules []types.RuleWithContent, language string) []types.RuleWithContent
+	RulesWithTag(tag string, ignoreCase bool) ([]types.RuleWithContent, error)
+	RuleHitFrequency(limit int) []RuleFrequency
+	ReportHistory(clusterName types.ClusterName, limit int) []ReportSnapshot
+	ReportSnapshotAt(clusterName types.ClusterName, at time.Time) (ReportSnapshot, bool)
+	ClusterInfo(clusterName types.ClusterName) ClusterInfo
+	Stats() StorageStats
+	LoadedFiles() []LoadedFile
+	ReportChangedSince(clusterName types.ClusterName, since time.Time) bool
+	PrecompressedReport(clusterName types.ClusterName, report types.ClusterReport) ([]byte, bool)
+	Reload() ReloadResult
+	RuleHitStatsForOrg(orgID types.OrgID) (OrgRuleHitStats, error)
+	ReportReadyIn(clusterName types.ClusterName, delay time.Duration) time.Duration
+	ResetSlowClusters()
+	SetOrganizationDenied(orgID types.OrgID, denied bool)
+	ResetDeniedOrganizations()
+	SetClusterDeleted(clusterName types.ClusterName, deleted bool)
+	IsDeletedCluster(clusterName types.ClusterName) bool
+	ResetDeletedClusters()
+	MergePatchReport(clusterName types.ClusterName, patch interface{}) (types.ClusterReport, error)
+	DeleteReportForCluster(clusterName types.ClusterName) error
+	RestoreDeletedReports()
+	ClustersMatchingPattern(glob string) ([]types.ClusterName, error)
+	GetChangingClusterVariant(clusterName types.ClusterName) (ChangingClusterVariant, bool)
+	Clock() clock.Clock
+	TimestampClock() clock.Clock
 }
 
 // MemoryStorage data structure represents configuration of memory storage used
 // to store mock data.
 type MemoryStorage struct {
+	path string
+	// clock is used by all time dependent parts of storage, timestampClock
+	// is used for generated timestamps (they differ by configured clock
+	// skew)
+	clock          clock.Clock
+	timestampClock clock.Clock
+	// number of synthetic clusters generated for given organization
+	syntheticClustersOrgID types.OrgID
+	syntheticClustersCount int
+	// ruleTimestamps enables created_at timestamps in rule hits
+	ruleTimestamps bool
+	// deletedClusters is shared by all copies of the storage
+	deletedClusters *deletedClusterSet
+}
+
+// Options contains settings of memory storage that are not read from mock
+// data directory
+type Options struct {
+	// Clock is used by all time dependent parts of storage, real time is
+	// used when not set
+	Clock clock.Clock
+	// TimestampClock is used for generated timestamps, Clock is used when
+	// not set
+	TimestampClock clock.Clock
+	// SyntheticClustersCount synthetic clusters are generated for
+	// SyntheticClustersOrgID organization, zero count disables them
+	SyntheticClustersOrgID types.OrgID
+	SyntheticClustersCount int
+	// RuleTimestamps enables adding of created_at timestamps to rule hits
+	RuleTimestamps bool
+	// DeletedClusters are treated as if no report exists for them, they
+	// are used again when deleted clusters are reset
+	DeletedClusters []types.ClusterName
 }
 
 // Special clusters can change results in given time period, for example each
 // 10 minutes or so. This is to simulate real world behaviour.
 const changingClustersPeriodInMinutes = 15
 
-var reports map[string]string = make(map[string]string)
+// reports and reportTemplates maps are never modified once loaded, they are
+// replaced as a whole on reload, so readers see either old or new data
+var (
+	reports      map[string]string = make(map[string]string)
+	reportsMutex sync.RWMutex
+	// reportsGeneration is incremented each time the reports are replaced,
+	// so data computed from reports can be cached
+	reportsGeneration uint64
+	// deletedReports contains reports deleted at runtime, so they can be
+	// restored
+	deletedReports = make(map[string]string)
+)
+
+// loadedReports returns map with all currently loaded reports
+func loadedReports() map[string]string {
+	reportsMutex.RLock()
+	defer reportsMutex.RUnlock()
+	return reports
+}
+
+// swapReports replaces currently loaded reports and report templates
+func swapReports(newReports map[string]string, newTemplates map[string]*template.Template) {
+	reportsMutex.Lock()
+	defer reportsMutex.Unlock()
+	reports = newReports
+	reportTemplates = newTemplates
+	deletedReports = make(map[string]string)
+	reportsGeneration++
+}
+
+// reportsChanged increments generation of reports, so data computed from
+// reports are computed again. It needs to be called after each change that
+// affects results of ListOfClustersForOrg or ReadReportForCluster.
+func reportsChanged() {
+	reportsMutex.Lock()
+	defer reportsMutex.Unlock()
+	reportsGeneration++
+}
+
+// loadedReportsGeneration returns generation of currently loaded reports
+func loadedReportsGeneration() uint64 {
+	reportsMutex.RLock()
+	defer reportsMutex.RUnlock()
+	return reportsGeneration
+}
+
+// reportFileName returns name of file with report for given cluster
+func reportFileName(clusterName string) string {
+	return reportFilePrefix + clusterName + reportFileSuffix
+}
 
-func readReport(path string, clusterName string) (string, error) {
-	absPath, err := filepath.Abs(path + "/report_" + clusterName + ".json")
+func readReport(files fs.FS, clusterName string) (string, error) {
+	report, err := fs.ReadFile(files, reportFileName(clusterName))
 	if err != nil {
 		return "", err
 	}
-	// disable "G304 (CWE-22): Potential file inclusion via variable"
-	// #nosec G304
-	report, err := ioutil.ReadFile(absPath)
+	return string(report), nil
+}
+
+// dataFiles returns file system with data files stored in given directory.
+// Dataset embedded into the service is used when the path is not set or
+// does not exist.
+func dataFiles(path string) fs.FS {
+	if path == "" {
+		log.Info().Msg("Path to mock data is not set, using embedded dataset")
+		return data.Files
+	}
+
+	_, err := os.Stat(path)
 	if err != nil {
-		return "", err
+		log.Warn().Err(err).Str("path", path).Msg("Mock data are not accessible, using embedded dataset")
+		return data.Files
 	}
-	return string(report), nil
+
+	return os.DirFS(path)
 }
 
 func init() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 }
 
-func initStorage(path string) error {
-	clusters := []string{
+// clustersWithReportFiles returns list of clusters that have reports stored
+// in data files
+func clustersWithReportFiles() []string {
+	return []string{
 		"34c3ecc5-624a-49a5-bab8-4fdc5e51a266",
 		"34c3ecc5-624a-49a5-bab8-4fdc5e51a267",
 		"34c3ecc5-624a-49a5-bab8-4fdc5e51a268",
@@ -159,20 +295,70 @@ func initStorage(path string) error {
 		"00000003-8d6a-43cc-b82c-7007664bdf69",
 		"00000003-eeee-eeee-eeee-000000000001",
 	}
-	for _, cluster := range clusters {
-		report, err := readReport(path, cluster)
-		if err != nil {
-			return err
-		}
-		reports[cluster] = report
+}
+
+func initStorage(path string) error {
+	loaded, failures, templates, err := readReportsAndTemplates(path)
+	if len(failures) > 0 {
+		return failures[0].cause
 	}
+	if err != nil {
+		return err
+	}
+
+	organizations, err := loadOrganizations(path)
+	if err != nil {
+		return err
+	}
+
+	content, err := loadRuleContent(path)
+	if err != nil {
+		return err
+	}
+
+	translations, err := loadRuleContentTranslations(path)
+	if err != nil {
+		return err
+	}
+
+	displayNames, err := loadClusterDisplayNames(path)
+	if err != nil {
+		return err
+	}
+
+	// rule content needs to be swapped before reports, because caches are
+	// invalidated when reports are swapped
+	swapRuleContent(content)
+	swapRuleContentTranslations(translations)
+	swapReports(loaded, templates)
+	swapLoadedFiles(describeLoadedFiles(path, loaded))
+	swapPrecompressedReports(loadPrecompressedReports(path, loaded))
+	swapReportHistory(loadReportHistory(path))
+	swapClusterInfo(loadClusterInfo(path))
+	swapOrganizations(organizations)
+	swapClusterDisplayNames(displayNames)
 	return nil
 }
 
 // New function creates and initializes a new instance of Storage interface
-func New(path string) (*MemoryStorage, error) {
+func New(path string, options Options) (*MemoryStorage, error) {
+	if options.Clock == nil {
+		options.Clock = clock.RealClock{}
+	}
+	if options.TimestampClock == nil {
+		options.TimestampClock = options.Clock
+	}
+
 	err := initStorage(path)
-	return &MemoryStorage{}, err
+	return &MemoryStorage{
+		path:                   path,
+		clock:                  options.Clock,
+		timestampClock:         options.TimestampClock,
+		syntheticClustersOrgID: options.SyntheticClustersOrgID,
+		syntheticClustersCount: options.SyntheticClustersCount,
+		ruleTimestamps:         options.RuleTimestamps,
+		deletedClusters:        newDeletedClusterSet(options.DeletedClusters),
+	}, err
 }
 
 // Init performs all database initialization
@@ -208,136 +394,226 @@ func (storage MemoryStorage) ListOfOrgs() ([]types.OrgID, error) {
 	return orgs, nil
 }
 
-func clustersForOrganization11789772() []types.ClusterName {
-	clusters := make([]types.ClusterName, 0)
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a266")
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a267")
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a268")
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a269")
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a26a")
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a26b")
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a26c")
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a26d")
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a26e")
-	clusters = append(clusters, "34c3ecc5-624a-49a5-bab8-4fdc5e51a26f")
-	clusters = append(clusters, "74ae54aa-6577-4e80-85e7-697cb646ff37")
-	clusters = append(clusters, "a7467445-8d6a-43cc-b82c-7007664bdf69")
-	clusters = append(clusters, "ee7d2bf4-8933-4a3a-8634-3328fe806e08")
-	clusters = append(clusters, "eeeeeeee-eeee-eeee-eeee-000000000001")
-	return clusters
-}
-
 // ListOfClustersForOrg reads list of all clusters fro given organization
 func (storage MemoryStorage) ListOfClustersForOrg(orgID types.OrgID) ([]types.ClusterName, error) {
 	clusters := make([]types.ClusterName, 0)
-	switch orgID {
-	case 11940171:
-		return clusters, errors.New("You have no permissions to get or change info about this organization")
-	case 11789772:
-		return clustersForOrganization11789772(), nil
-	case 1:
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a266")
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a267")
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a268")
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a269")
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a26a")
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a26b")
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a26c")
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a26d")
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a26e")
-		clusters = append(clusters, "00000001-624a-49a5-bab8-4fdc5e51a26f")
-		clusters = append(clusters, "00000001-6577-4e80-85e7-697cb646ff37")
-		clusters = append(clusters, "00000001-8933-4a3a-8634-3328fe806e08")
-		clusters = append(clusters, "00000001-8d6a-43cc-b82c-7007664bdf69")
-		clusters = append(clusters, "00000001-eeee-eeee-eeee-000000000001")
-	case 2:
-		clusters = append(clusters, "00000002-624a-49a5-bab8-4fdc5e51a266")
-		clusters = append(clusters, "00000002-6577-4e80-85e7-697cb646ff37")
-		clusters = append(clusters, "00000002-8933-4a3a-8634-3328fe806e08")
-	case 3:
-		clusters = append(clusters, "00000003-8933-4a3a-8634-3328fe806e08")
-		clusters = append(clusters, "00000003-8d6a-43cc-b82c-7007664bdf69")
-		clusters = append(clusters, "00000003-eeee-eeee-eeee-000000000001")
+	if isForbiddenOrg(orgID) {
+		return clusters, errNoPermissions
 	}
 
-	return clusters, nil
+	clusters = append(clusters, loadedOrganizations()[orgID]...)
+	clusters = append(clusters, storage.syntheticClusters(orgID)...)
+	return storage.withoutDeletedClusters(clusters), nil
+}
+
+// postprocessReport filters out rules disabled for given cluster and adds
+// rule hit timestamps when enabled
+func (storage MemoryStorage) postprocessReport(clusterName types.ClusterName, report types.ClusterReport) (types.ClusterReport, error) {
+	report, err := filterDisabledRules(clusterName, report)
+	if err != nil {
+		return report, err
+	}
+	return storage.addRuleTimestampsWhenEnabled(report)
 }
 
-// GetOrgIDByClusterID reads OrgID for specified cluster
+// GetOrgIDByClusterID reads OrgID for specified cluster. When the cluster
+// belongs to more organizations, the one with the lowest ID is returned.
 func (storage MemoryStorage) GetOrgIDByClusterID(cluster types.ClusterName) (types.OrgID, error) {
 	var orgID uint64 = 42
 
+	owners := storage.ownersOfCluster(cluster)
+	if len(owners) > 0 {
+		return owners[0], nil
+	}
+
 	return types.OrgID(orgID), nil
 }
 
-func getReportForCluster(clusterName types.ClusterName) string {
-	report, ok := reports[string(clusterName)]
+// OwnersOfCluster returns sorted list of all organizations owning given
+// cluster
+func (storage MemoryStorage) OwnersOfCluster(cluster types.ClusterName) []types.OrgID {
+	return storage.ownersOfCluster(cluster)
+}
+
+func (storage MemoryStorage) getReportForCluster(clusterName types.ClusterName) string {
+	clusterName = NormalizeClusterName(clusterName)
+
+	// synthetic clusters share reports with real clusters
+	if reportName, synthetic := storage.syntheticClusterReportName(clusterName); synthetic {
+		clusterName = reportName
+	}
+
+	report, ok := loadedReports()[string(clusterName)]
 	if !ok {
 		return ""
 	}
 	return report
 }
 
+// clusters that can change its output (report)
+// please note that these clusters have special name:
+// "cccccccc-cccc-cccc-cccc-{index}"
+//
+// Mnemotechnic: c - changing
+var changingClusters = map[string][]string{
+	"cccccccc-cccc-cccc-cccc-000000000001": {
+		"34c3ecc5-624a-49a5-bab8-4fdc5e51a266",
+		"74ae54aa-6577-4e80-85e7-697cb646ff37",
+		"a7467445-8d6a-43cc-b82c-7007664bdf69"},
+	"cccccccc-cccc-cccc-cccc-000000000002": {
+		"74ae54aa-6577-4e80-85e7-697cb646ff37",
+		"a7467445-8d6a-43cc-b82c-7007664bdf69",
+		"ee7d2bf4-8933-4a3a-8634-3328fe806e08"},
+	"cccccccc-cccc-cccc-cccc-000000000003": {
+		"ee7d2bf4-8933-4a3a-8634-3328fe806e08",
+		"ee7d2bf4-8933-4a3a-8634-3328fe806e08",
+		"34c3ecc5-624a-49a5-bab8-4fdc5e51a266"},
+	"cccccccc-cccc-cccc-cccc-000000000004": {
+		"eeeeeeee-eeee-eeee-eeee-000000000001",
+		"eeeeeeee-eeee-eeee-eeee-000000000001",
+		"34c3ecc5-624a-49a5-bab8-4fdc5e51a266"},
+}
+
 // ReadReportForCluster reads result (health status) for selected cluster
 func (storage MemoryStorage) ReadReportForCluster(
 	clusterName types.ClusterName,
+) (types.ClusterReport, error) {
+	return storage.readReportForCluster(clusterName, storage.timestampNow())
+}
+
+// ReadStableReportForCluster reads result for selected cluster like
+// ReadReportForCluster, but report template is rendered at fixed time. Such
+// report changes only when the report itself changes, not with time of
+// rendering, so it can be used to detect changes of reports.
+func (storage MemoryStorage) ReadStableReportForCluster(
+	clusterName types.ClusterName,
+) (types.ClusterReport, error) {
+	return storage.readReportForCluster(clusterName, time.Time{})
+}
+
+// readReportForCluster reads result for selected cluster, report template is
+// rendered at given time
+func (storage MemoryStorage) readReportForCluster(
+	clusterName types.ClusterName, renderedAt time.Time,
 ) (types.ClusterReport, error) {
 	var report string
 
-	// clusters that can change its output (report)
-	// please note that these clusters have special name:
-	// "cccccccc-cccc-cccc-cccc-{index}"
-	//
-	// Mnemotechnic: c - changing
-	changingClusters := map[string][]string{
-		"cccccccc-cccc-cccc-cccc-000000000001": {
-			"34c3ecc5-624a-49a5-bab8-4fdc5e51a266",
-			"74ae54aa-6577-4e80-85e7-697cb646ff37",
-			"a7467445-8d6a-43cc-b82c-7007664bdf69"},
-		"cccccccc-cccc-cccc-cccc-000000000002": {
-			"74ae54aa-6577-4e80-85e7-697cb646ff37",
-			"a7467445-8d6a-43cc-b82c-7007664bdf69",
-			"ee7d2bf4-8933-4a3a-8634-3328fe806e08"},
-		"cccccccc-cccc-cccc-cccc-000000000003": {
-			"ee7d2bf4-8933-4a3a-8634-3328fe806e08",
-			"ee7d2bf4-8933-4a3a-8634-3328fe806e08",
-			"34c3ecc5-624a-49a5-bab8-4fdc5e51a266"},
-		"cccccccc-cccc-cccc-cccc-000000000004": {
-			"eeeeeeee-eeee-eeee-eeee-000000000001",
-			"eeeeeeee-eeee-eeee-eeee-000000000001",
-			"34c3ecc5-624a-49a5-bab8-4fdc5e51a266"},
+	// deleted cluster has no report even when report file exists
+	if storage.IsDeletedCluster(clusterName) {
+		return types.ClusterReport(report), nil
 	}
 
+	clusterName = NormalizeClusterName(clusterName)
 	reportName := clusterName
 
 	// handling for clusters that can change its report
 	if changingCluster, found := changingClusters[string(clusterName)]; found {
-		reportName = chooseReport(changingCluster)
+		reportName = storage.chooseReport(clusterName, changingCluster)
 	}
 
-	report = getReportForCluster(reportName)
+	report = storage.getReportForCluster(reportName)
+
+	// cluster without its own report might be served by report template
+	if report == "" {
+		if tmpl, found := findReportTemplate(clusterName); found {
+			rendered, err := renderReportTemplate(tmpl, clusterName, renderedAt)
+			if err != nil {
+				return types.ClusterReport(""), err
+			}
+			report = rendered
+		}
+	}
 
-	return types.ClusterReport(report), nil
+	return storage.postprocessReport(clusterName, types.ClusterReport(report))
 }
 
-// chooseReport for "changing cluster"
-func chooseReport(variants []string) types.ClusterName {
-	const operationName = "changingCluster"
+// ChangingClusterVariant represents the report variant currently served for
+// "changing cluster"
+type ChangingClusterVariant struct {
+	Cluster        types.ClusterName `json:"cluster"`
+	Variant        types.ClusterName `json:"variant"`
+	Index          int               `json:"index"`
+	NextRotationIn float64           `json:"next_rotation_in_seconds"`
+	NextRotationAt string            `json:"next_rotation_at"`
+}
 
-	// first we need to get the minute in hour
-	currentTime := time.Now()
-	minute := currentTime.Minute()
-	log.Info().Int("Minute in hour", minute).Msg(operationName)
+// changingClusterOffset computes phase offset (in minutes) for given
+// "changing cluster" so all such clusters don't rotate at the same time. The
+// offset is derived from cluster name, so it is stable between restarts.
+func changingClusterOffset(clusterName types.ClusterName) int {
+	hash := fnv.New32a()
+	// writing into hash never returns an error
+	_, _ = hash.Write([]byte(clusterName))
+	return int(hash.Sum32() % changingClustersPeriodInMinutes)
+}
+
+// computeChangingClusterVariant computes which report variant is served for
+// "changing cluster" at given time and when the next rotation will happen
+func computeChangingClusterVariant(
+	clusterName types.ClusterName, variants []string, currentTime time.Time,
+) ChangingClusterVariant {
+	// first we need to get the minute in hour shifted by cluster-specific
+	// offset
+	offset := changingClusterOffset(clusterName)
+	minute := (currentTime.Minute() + offset) % 60
 
 	// then compute index of report
-	i := minute / changingClustersPeriodInMinutes
-	i %= len(variants)
+	slot := minute / changingClustersPeriodInMinutes
+	i := slot % len(variants)
+
+	// time elapsed since the beginning of the current (shifted) minute
+	elapsed := time.Duration(currentTime.Second())*time.Second +
+		time.Duration(currentTime.Nanosecond())
+	nextRotationIn := time.Duration((slot+1)*changingClustersPeriodInMinutes-minute)*time.Minute - elapsed
+
+	return ChangingClusterVariant{
+		Cluster:        clusterName,
+		Variant:        types.ClusterName(variants[i]),
+		Index:          i,
+		NextRotationIn: nextRotationIn.Seconds(),
+		NextRotationAt: currentTime.Add(nextRotationIn).UTC().Format(time.RFC3339),
+	}
+}
+
+// GetChangingClusterVariant returns the report variant currently served for
+// given "changing cluster". False is returned for other clusters.
+func (storage MemoryStorage) GetChangingClusterVariant(clusterName types.ClusterName) (ChangingClusterVariant, bool) {
+	variants, found := changingClusters[string(clusterName)]
+	if !found {
+		return ChangingClusterVariant{}, false
+	}
+	return computeChangingClusterVariant(clusterName, variants, storage.now()), true
+}
+
+// changingClustersVariants returns indexes of report variants currently
+// served for all "changing clusters", so data computed from their reports
+// can be cached until any of them is rotated
+func (storage MemoryStorage) changingClustersVariants() string {
+	clusters := make([]string, 0, len(changingClusters))
+	for cluster := range changingClusters {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	currentTime := storage.now()
+	indexes := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		variant := computeChangingClusterVariant(types.ClusterName(cluster), changingClusters[cluster], currentTime)
+		indexes[i] = strconv.Itoa(variant.Index)
+	}
+	return strings.Join(indexes, ",")
+}
+
+// chooseReport for "changing cluster"
+func (storage MemoryStorage) chooseReport(clusterName types.ClusterName, variants []string) types.ClusterName {
+	const operationName = "changingCluster"
+
+	variant := computeChangingClusterVariant(clusterName, variants, storage.now())
 
 	// and choose the report according to the index
-	cluster := variants[i]
-	log.Info().Int("Index", i).Msg(operationName)
-	log.Info().Str("Cluster", cluster).Msg(operationName)
-	return types.ClusterName(cluster)
+	log.Info().Int("Index", variant.Index).Msg(operationName)
+	log.Info().Str("Cluster", string(variant.Variant)).Msg(operationName)
+	return variant.Variant
 }
 
 // ReadReportForOrganizationAndCluster reads result (health status) for
@@ -347,20 +623,27 @@ func (storage MemoryStorage) ReadReportForOrganizationAndCluster(
 ) (types.ClusterReport, error) {
 	var report string
 
-	switch orgID {
-	case 11940171:
-		return types.ClusterReport(report), errors.New("You have no permissions to get or change info about this organization")
-	case 1:
-		fallthrough
-	case 2:
-		fallthrough
-	case 3:
-		fallthrough
-	case 11789772:
-		report = getReportForCluster(clusterName)
+	if isForbiddenOrg(orgID) {
+		return types.ClusterReport(report), errNoPermissions
+	}
+
+	clusterName = NormalizeClusterName(clusterName)
+	if storage.IsDeletedCluster(clusterName) {
+		return types.ClusterReport(report), nil
+	}
+
+	if _, known := loadedOrganizations()[orgID]; !known {
+		return types.ClusterReport(report), nil
+	}
+
+	// cluster owned by other organization(s) can't be read
+	owners := storage.ownersOfCluster(clusterName)
+	if len(owners) > 0 && !containsOrg(owners, orgID) {
+		return types.ClusterReport(report), errClusterNotInOrg
 	}
 
-	return types.ClusterReport(report), nil
+	report = storage.getReportForCluster(clusterName)
+	return storage.postprocessReport(clusterName, types.ClusterReport(report))
 }
 
 // ReadReportForClusterByClusterName reads result (health status) for selected cluster for given organization
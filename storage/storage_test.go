@@ -14,3 +14,605 @@ See the License for the specific language governing permissions and
 */
 
 package storage_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/clock"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/storage"
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// TestChangingClusterRotation checks that report variant of changing cluster
+// is rotated at the announced time
+func TestChangingClusterRotation(t *testing.T) {
+	mockClock := clock.NewMockClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	s, err := storage.New("", storage.Options{Clock: mockClock})
+	assert.NoError(t, err)
+
+	cluster := types.ClusterName("cccccccc-cccc-cccc-cccc-000000000001")
+	current, found := s.GetChangingClusterVariant(cluster)
+	assert.True(t, found)
+
+	mockClock.Advance(time.Duration(current.NextRotationIn * float64(time.Second)))
+	next, _ := s.GetChangingClusterVariant(cluster)
+	assert.NotEqual(t, current.Index, next.Index)
+}
+
+// TestConvertReportToV1AndBack checks that report is converted into legacy
+// v1 "flat" schema and back without any change
+func TestConvertReportToV1AndBack(t *testing.T) {
+	report := types.ClusterReport(`{
+		"reports": {
+			"meta": {"count": 1, "last_checked_at": "2020-05-27T14:15:35Z"},
+			"data": [{"rule_id": "ccx_rules_ocp.external.rules.nodes_kubelet_version_check", "total_risk": 2}]
+		},
+		"status": "ok"
+	}`)
+	flatReport := types.ClusterReport(`{
+		"count": 1,
+		"last_checked_at": "2020-05-27T14:15:35Z",
+		"reports": [{"rule_id": "ccx_rules_ocp.external.rules.nodes_kubelet_version_check", "total_risk": 2}],
+		"status": "ok"
+	}`)
+
+	converted, err := storage.ConvertReportToV1(report)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(flatReport), string(converted))
+
+	converted, err = storage.ConvertReportFromV1(flatReport)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(report), string(converted))
+
+	_, err = storage.ConvertReportToV1(flatReport)
+	assert.Error(t, err)
+}
+
+func TestAddRuleTimestamps(t *testing.T) {
+	report := types.ClusterReport(`{
+		"reports": {
+			"meta": {"count": 2, "last_checked_at": "2020-05-27T14:15:35Z"},
+			"data": [
+				{"rule_id": "rule.one", "details": {"error_key": "KEY"}},
+				{"rule_id": "rule.two", "details": {"error_key": "KEY"}}
+			]
+		},
+		"status": "ok"
+	}`)
+	checkedAt, _ := time.Parse(time.RFC3339, "2020-05-27T14:15:35Z")
+
+	enriched, err := storage.AddRuleTimestamps(report)
+	assert.NoError(t, err)
+
+	var parsed struct {
+		Reports struct {
+			Data []struct {
+				CreatedAt time.Time `json:"created_at"`
+			} `json:"data"`
+		} `json:"reports"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(enriched), &parsed))
+	assert.Len(t, parsed.Reports.Data, 2)
+	for _, hit := range parsed.Reports.Data {
+		assert.True(t, hit.CreatedAt.Before(checkedAt))
+		assert.True(t, hit.CreatedAt.After(checkedAt.Add(-31*24*time.Hour)))
+	}
+
+	// timestamps have to be the same for each call
+	again, err := storage.AddRuleTimestamps(report)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(enriched), string(again))
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600)
+		assert.NoError(t, err)
+	}
+
+	writeFile("report_34c3ecc5-624a-49a5-bab8-4fdc5e51a266.json", `{"reports": {"data": []}}`)
+	writeFile("report_34c3ecc5-624a-49a5-bab8-4fdc5e51a267.json", "{\n  \"reports\": {\n    \"data\": [],\n  }\n}")
+	// templates are not valid JSON before rendering
+	writeFile("report_template_00000004.json", `{"cluster": {{.ClusterName}}}`)
+
+	validationErrors := storage.Validate(dir)
+	assert.Len(t, validationErrors, 1)
+	assert.Contains(t, validationErrors[0].Error(), "report_34c3ecc5-624a-49a5-bab8-4fdc5e51a267.json:4:3:")
+	assert.Contains(t, validationErrors[0].Error(), `"}"`)
+
+	// embedded dataset is valid
+	assert.Empty(t, storage.Validate(""))
+
+	assert.Len(t, storage.Validate(filepath.Join(dir, "does-not-exist")), 1)
+}
+
+func TestRuleContentFromContentServiceLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		name = filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(name), 0o700))
+		assert.NoError(t, os.WriteFile(name, []byte(content), 0o600))
+	}
+
+	// all report files are needed by storage
+	reports, err := filepath.Glob("../data/report_*.json")
+	assert.NoError(t, err)
+	for _, report := range reports {
+		content, err := os.ReadFile(report)
+		assert.NoError(t, err)
+		writeFile(filepath.Base(report), string(content))
+	}
+
+	pluginDir := "content/external/rules/node_installer_degraded/"
+	writeFile("content/config.yaml", "impact:\n  Application Failure: 3\n")
+	writeFile(pluginDir+"plugin.yaml", "name: Node installer degraded\npython_module: ccx_rules_ocp.external.rules.node_installer_degraded.report\n")
+	writeFile(pluginDir+"summary.md", "Plugin summary\n")
+	writeFile(pluginDir+"reason.md", "Plugin reason\n")
+	writeFile(pluginDir+"NODE_INSTALLER_DEGRADED/metadata.yaml", `condition: Installer pods are removed
+description: Production description
+impact: Application Failure
+likelihood: 2
+publish_date: 2020-04-08 00:42:00
+status: active
+tags:
+  - openshift
+`)
+	writeFile(pluginDir+"NODE_INSTALLER_DEGRADED/generic.md", "Generic text\n")
+	writeFile(pluginDir+"NODE_INSTALLER_DEGRADED/reason.md", "Error key reason\n")
+
+	s, err := storage.New(dir, storage.Options{})
+	assert.NoError(t, err)
+	defer func() {
+		_, err := storage.New("", storage.Options{})
+		assert.NoError(t, err)
+	}()
+
+	rule, err := s.GetRuleWithContent("ccx_rules_ocp.external.rules.node_installer_degraded", "NODE_INSTALLER_DEGRADED")
+	assert.NoError(t, err)
+	assert.Equal(t, "Node installer degraded", rule.Name)
+	assert.Equal(t, "Production description", rule.Description)
+	assert.Equal(t, "Plugin summary", rule.Summary)
+	assert.Equal(t, "Error key reason", rule.Reason)
+	assert.Equal(t, "Generic text", rule.Generic)
+	assert.Equal(t, 2, rule.TotalRisk)
+	assert.True(t, rule.Active)
+	assert.Equal(t, []string{"openshift"}, rule.Tags)
+	assert.Equal(t, time.Date(2020, 4, 8, 0, 42, 0, 0, time.UTC), rule.PublishDate)
+
+	rules, err := s.ListOfRulesWithContent()
+	assert.NoError(t, err)
+	assert.Contains(t, rules, *rule)
+
+	// rules without content are taken from reports
+	_, err = s.GetRuleWithContent("ccx_rules_ocm.tutorial_rule", "TUTORIAL_ERROR")
+	assert.NoError(t, err)
+
+	_, err = s.GetRuleWithContent("unknown", "UNKNOWN")
+	assert.Error(t, err)
+}
+
+// TestRuleContentTranslations checks that texts of rule content are
+// translated when translation is available and kept in the default language
+// otherwise
+func TestRuleContentTranslations(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		name = filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(name), 0o700))
+		assert.NoError(t, os.WriteFile(name, []byte(content), 0o600))
+	}
+
+	// all report files are needed by storage
+	reports, err := filepath.Glob("../data/report_*.json")
+	assert.NoError(t, err)
+	for _, report := range reports {
+		content, err := os.ReadFile(report)
+		assert.NoError(t, err)
+		writeFile(filepath.Base(report), string(content))
+	}
+
+	const ruleID = "ccx_rules_ocp.external.rules.node_installer_degraded"
+	for _, contentDir := range []string{"content", "content_de"} {
+		pluginDir := contentDir + "/external/rules/node_installer_degraded/"
+		writeFile(pluginDir+"plugin.yaml", "name: Node installer degraded\npython_module: "+ruleID+".report\n")
+		writeFile(pluginDir+"NODE_INSTALLER_DEGRADED/metadata.yaml", "impact: 2\nlikelihood: 2\nstatus: active\n")
+	}
+	writeFile("content/external/rules/node_installer_degraded/summary.md", "Summary\n")
+	writeFile("content/external/rules/node_installer_degraded/reason.md", "Reason\n")
+	writeFile("content_de/external/rules/node_installer_degraded/summary.md", "Zusammenfassung\n")
+
+	s, err := storage.New(dir, storage.Options{})
+	assert.NoError(t, err)
+	defer func() {
+		_, err := storage.New("", storage.Options{})
+		assert.NoError(t, err)
+	}()
+
+	assert.Equal(t, []string{"de"}, s.RuleContentLanguages())
+
+	rules, err := s.RuleContent(ruleID)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+
+	localized := s.LocalizeRules(rules, "de")
+	assert.Equal(t, "Zusammenfassung", localized[0].Summary)
+	assert.Equal(t, "Reason", localized[0].Reason)
+	assert.Equal(t, "Summary", rules[0].Summary)
+
+	assert.Equal(t, rules, s.LocalizeRules(rules, "fr"))
+
+	_, err = s.RuleContent("unknown")
+	assert.Error(t, err)
+}
+
+// TestResolveClusterName checks that clusters can be referenced by display
+// names read from data directory and that display names can't look like
+// cluster UUIDs
+func TestResolveClusterName(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	// all report files are needed by storage
+	reports, err := filepath.Glob("../data/report_*.json")
+	assert.NoError(t, err)
+	for _, report := range reports {
+		content, err := os.ReadFile(report)
+		assert.NoError(t, err)
+		writeFile(filepath.Base(report), string(content))
+	}
+
+	writeFile("cluster_names.json", `{"prod-east": "34C3ECC5-624A-49A5-BAB8-4FDC5E51A266"}`)
+
+	s, err := storage.New(dir, storage.Options{})
+	assert.NoError(t, err)
+	defer func() {
+		_, err := storage.New("", storage.Options{})
+		assert.NoError(t, err)
+	}()
+
+	cluster, err := s.ResolveClusterName("prod-east")
+	assert.NoError(t, err)
+	assert.Equal(t, types.ClusterName("34c3ecc5-624a-49a5-bab8-4fdc5e51a266"), cluster)
+
+	cluster, err = s.ResolveClusterName("74ae54aa-6577-4e80-85e7-697cb646ff37")
+	assert.NoError(t, err)
+	assert.Equal(t, types.ClusterName("74ae54aa-6577-4e80-85e7-697cb646ff37"), cluster)
+
+	_, err = s.ResolveClusterName("prod-west")
+	assert.Error(t, err)
+
+	writeFile("cluster_names.json", `{"74ae54aa-6577-4e80-85e7-697cb646ff37": "34c3ecc5-624a-49a5-bab8-4fdc5e51a266"}`)
+	_, err = storage.New(dir, storage.Options{})
+	assert.Error(t, err)
+}
+
+// TestRuleHitStatsForOrgInvalidation checks that cached rule hit statistics
+// of organization follow deleted clusters, denied organizations, rule toggles
+// and rotation of "changing clusters"
+func TestRuleHitStatsForOrgInvalidation(t *testing.T) {
+	const orgID = types.OrgID(7)
+	const cluster = types.ClusterName("74ae54aa-6577-4e80-85e7-697cb646ff37")
+
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	// all report files are needed by storage
+	reports, err := filepath.Glob("../data/report_*.json")
+	assert.NoError(t, err)
+	for _, report := range reports {
+		content, err := os.ReadFile(report)
+		assert.NoError(t, err)
+		writeFile(filepath.Base(report), string(content))
+	}
+
+	writeFile("organizations.json", `{"7": ["`+string(cluster)+`", "cccccccc-cccc-cccc-cccc-000000000004"]}`)
+
+	mockClock := clock.NewMockClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	s, err := storage.New(dir, storage.Options{Clock: mockClock})
+	assert.NoError(t, err)
+	defer func() {
+		_, err := storage.New("", storage.Options{})
+		assert.NoError(t, err)
+	}()
+
+	// number of rule hits in all reports of organization read directly
+	expectedHits := func() int {
+		clusters, err := s.ListOfClustersForOrg(orgID)
+		assert.NoError(t, err)
+
+		hits := 0
+		for _, cluster := range clusters {
+			report, err := s.ReadReportForCluster(cluster)
+			assert.NoError(t, err)
+			count, err := storage.CountReportRuleHits(report)
+			assert.NoError(t, err)
+			hits += count
+		}
+		return hits
+	}
+
+	// number of rule hits according to (cached) statistics
+	statsHits := func() int {
+		stats, err := s.RuleHitStatsForOrg(orgID)
+		assert.NoError(t, err)
+
+		hits := 0
+		for _, rule := range stats.Rules {
+			hits += rule.ClustersHit
+		}
+		return hits
+	}
+
+	initialHits := statsHits()
+	assert.Equal(t, expectedHits(), initialHits)
+
+	s.SetClusterDeleted(cluster, true)
+	assert.Equal(t, expectedHits(), statsHits())
+	assert.NotEqual(t, initialHits, statsHits())
+	s.ResetDeletedClusters()
+	assert.Equal(t, initialHits, statsHits())
+
+	stats, err := s.RuleHitStatsForOrg(orgID)
+	assert.NoError(t, err)
+	ruleID := stats.Rules[0].RuleID
+	assert.NoError(t, s.ToggleRuleForCluster(cluster, ruleID, "user", storage.RuleToggleDisable))
+	assert.Equal(t, expectedHits(), statsHits())
+	assert.NotEqual(t, initialHits, statsHits())
+	assert.NoError(t, s.DeleteFromRuleClusterToggle(cluster, ruleID, "user"))
+	assert.Equal(t, initialHits, statsHits())
+
+	s.SetOrganizationDenied(orgID, true)
+	_, err = s.RuleHitStatsForOrg(orgID)
+	assert.Error(t, err)
+	s.ResetDeniedOrganizations()
+	assert.Equal(t, initialHits, statsHits())
+
+	// all report variants of changing cluster are served in one hour
+	seen := make(map[int]bool)
+	for i := 0; i < 4; i++ {
+		variant, found := s.GetChangingClusterVariant("cccccccc-cccc-cccc-cccc-000000000004")
+		assert.True(t, found)
+		assert.Equal(t, expectedHits(), statsHits())
+		seen[statsHits()] = true
+		mockClock.Advance(time.Duration(variant.NextRotationIn * float64(time.Second)))
+	}
+	assert.Len(t, seen, 2)
+}
+
+// testReportTemplate is report template used for clusters with name starting
+// with dddddddd
+const testReportTemplate = `{"reports": {"meta": {"count": 0, "last_checked_at": "{{.Now}}"}, "data": []}, "cluster": "{{.ClusterName}}", "status": "ok"}`
+
+// mockDataFiles returns content of all report files from mock data together
+// with report template, key is file name
+func mockDataFiles(t *testing.T) map[string][]byte {
+	files := map[string][]byte{
+		"report_template_dddddddd.json": []byte(testReportTemplate),
+	}
+
+	reports, err := filepath.Glob("../data/report_*.json")
+	assert.NoError(t, err)
+	for _, report := range reports {
+		content, err := os.ReadFile(report)
+		assert.NoError(t, err)
+		files[filepath.Base(report)] = content
+	}
+	return files
+}
+
+// writeArchive writes given files into tar.gz archive, the files are stored
+// in subdirectory
+func writeArchive(t *testing.T, path string, files map[string][]byte) {
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for name, content := range files {
+		header := &tar.Header{
+			Name:     "data/" + name,
+			Mode:     0o600,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}
+		assert.NoError(t, tarWriter.WriteHeader(header))
+		_, err := tarWriter.Write(content)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, tarWriter.Close())
+	assert.NoError(t, gzipWriter.Close())
+	assert.NoError(t, os.WriteFile(path, archive.Bytes(), 0o600))
+}
+
+// TestLoadReportsAndTemplates checks that reports, report templates and
+// historical reports are loaded both from directory and from tar.gz archive
+func TestLoadReportsAndTemplates(t *testing.T) {
+	files := mockDataFiles(t)
+
+	dir := t.TempDir()
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o600))
+	}
+
+	archive := filepath.Join(t.TempDir(), "data.tar.gz")
+	writeArchive(t, archive, files)
+
+	defer func() {
+		_, err := storage.New("", storage.Options{})
+		assert.NoError(t, err)
+	}()
+
+	for _, path := range []string{dir, archive} {
+		s, err := storage.New(path, storage.Options{})
+		assert.NoError(t, err, path)
+
+		report, err := s.ReadReportForCluster("34c3ecc5-624a-49a5-bab8-4fdc5e51a266")
+		assert.NoError(t, err, path)
+		assert.Equal(t, string(files["report_34c3ecc5-624a-49a5-bab8-4fdc5e51a266.json"]), string(report), path)
+
+		report, err = s.ReadReportForCluster("dddddddd-0000-0000-0000-000000000001")
+		assert.NoError(t, err, path)
+		assert.Contains(t, string(report), `"cluster": "dddddddd-0000-0000-0000-000000000001"`, path)
+
+		history := s.ReportHistory("34c3ecc5-624a-49a5-bab8-4fdc5e51a266", 0)
+		assert.Len(t, history, 3, path)
+
+		result := s.Reload()
+		assert.Empty(t, result.Failures, path)
+		// two templates from mock data and the test one
+		assert.Equal(t, 3, result.ReportTemplatesCount, path)
+	}
+}
+
+// TestRenderReportTemplate checks that report template with the longest
+// matching prefix is rendered for cluster without report file and that
+// templates failing to render are reported as errors
+func TestRenderReportTemplate(t *testing.T) {
+	files := mockDataFiles(t)
+	files["report_template_dddddddd-0000.json"] = []byte(`{"reports": {"meta": {"count": 0, "last_checked_at": "{{.Now}}"}, "data": []}, "template": "longer", "status": "ok"}`)
+	files["report_template_eeeeeeee-ffff.json"] = []byte(`{"cluster": "{{.Unknown}}"}`)
+	files["report_template_ffffffff.json"] = []byte(`{"cluster": {{.ClusterName}}}`)
+
+	dir := t.TempDir()
+	for name, content := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o600))
+	}
+
+	mockClock := clock.NewMockClock(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC))
+	s, err := storage.New(dir, storage.Options{
+		Clock:          mockClock,
+		TimestampClock: clock.NewSkewedClock(mockClock, time.Hour),
+	})
+	assert.NoError(t, err)
+	defer func() {
+		_, err := storage.New("", storage.Options{})
+		assert.NoError(t, err)
+	}()
+
+	report, err := s.ReadReportForCluster("dddddddd-1111-1111-1111-000000000001")
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"reports": {"meta": {"count": 0, "last_checked_at": "2021-01-01T13:00:00Z"}, "data": []},
+		"cluster": "dddddddd-1111-1111-1111-000000000001",
+		"status": "ok"
+	}`, string(report))
+
+	report, err = s.ReadReportForCluster("dddddddd-0000-1111-1111-000000000001")
+	assert.NoError(t, err)
+	assert.Contains(t, string(report), `"template": "longer"`)
+
+	// unknown field can't be evaluated
+	_, err = s.ReadReportForCluster("eeeeeeee-ffff-1111-1111-000000000001")
+	assert.Error(t, err)
+
+	// cluster name is not quoted
+	_, err = s.ReadReportForCluster("ffffffff-1111-1111-1111-000000000001")
+	assert.EqualError(t, err, "report template report_template_ffffffff.json rendered invalid JSON for cluster ffffffff-1111-1111-1111-000000000001")
+}
+
+// TestFilterReportByTotalRisk checks that total risk of rule hits is taken
+// from rule content and that total risk stored in rule hit is used for
+// unknown rules
+func TestFilterReportByTotalRisk(t *testing.T) {
+	report := types.ClusterReport(`{
+		"reports": {
+			"meta": {"count": 4, "last_checked_at": "2020-05-27T14:15:35Z"},
+			"data": [
+				{"rule_id": "rule.a", "details": {"error_key": "A"}, "total_risk": 1},
+				{"rule_id": "rule.b", "details": {"error_key": "B"}, "total_risk": 4},
+				{"rule_id": "rule.c", "details": {"error_key": "C"}, "total_risk": 3},
+				{"rule_id": "rule.d", "details": {"error_key": "D"}}
+			]
+		},
+		"status": "ok"
+	}`)
+	rules := []types.RuleWithContent{
+		{Module: "rule.a", ErrorKey: "A", TotalRisk: 4},
+		{Module: "rule.b", ErrorKey: "B", TotalRisk: 1},
+	}
+
+	filtered := func(minRisk int) []types.RuleSelector {
+		report, err := storage.FilterReportByTotalRisk(report, rules, minRisk)
+		assert.NoError(t, err)
+		selectors, err := storage.RuleHitSelectors(report)
+		assert.NoError(t, err)
+		return selectors
+	}
+
+	assert.Equal(t, []types.RuleSelector{"rule.a|A", "rule.b|B", "rule.c|C", "rule.d|D"}, filtered(0))
+	assert.Equal(t, []types.RuleSelector{"rule.a|A", "rule.c|C"}, filtered(3))
+	assert.Equal(t, []types.RuleSelector{"rule.a|A"}, filtered(4))
+	assert.Empty(t, filtered(5))
+}
+
+// TestInflateReport checks that report is inflated by clones of the first
+// rule hit with unique identifiers and that the number of clones is limited
+func TestInflateReport(t *testing.T) {
+	report := types.ClusterReport(`{
+		"reports": {
+			"meta": {"count": 1, "last_checked_at": "2020-05-27T14:15:35Z"},
+			"data": [{
+				"rule_id": "rule.a",
+				"details": {"error_key": "A", "nodes": [{"name": "node-1"}]},
+				"extra_data": {"versions": {"kubelet": "1.18"}}
+			}]
+		},
+		"status": "ok"
+	}`)
+
+	inflated, err := storage.InflateReport(report, 3)
+	assert.NoError(t, err)
+
+	var parsed struct {
+		Reports struct {
+			Data []map[string]interface{} `json:"data"`
+		} `json:"reports"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(inflated), &parsed))
+	assert.Len(t, parsed.Reports.Data, 4)
+
+	selectors, err := storage.RuleHitSelectors(inflated)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.RuleSelector{
+		"rule.a|A",
+		"rule.a.synthetic_1|A_SYNTHETIC_1",
+		"rule.a.synthetic_2|A_SYNTHETIC_2",
+		"rule.a.synthetic_3|A_SYNTHETIC_3",
+	}, selectors)
+	for _, hit := range parsed.Reports.Data {
+		assert.Equal(t, map[string]interface{}{"versions": map[string]interface{}{"kubelet": "1.18"}}, hit["extra_data"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"name": "node-1"}}, hit["details"].(map[string]interface{})["nodes"])
+	}
+
+	// synthetic rule hit is cloned when report does not contain any rule hit
+	empty := types.ClusterReport(`{"reports": {"meta": {"count": 0}, "data": []}, "status": "ok"}`)
+	inflated, err = storage.InflateReport(empty, storage.MaxReportInflation)
+	assert.NoError(t, err)
+	selectors, err = storage.RuleHitSelectors(inflated)
+	assert.NoError(t, err)
+	assert.Len(t, selectors, storage.MaxReportInflation)
+	unique := make(map[types.RuleSelector]bool, len(selectors))
+	for _, selector := range selectors {
+		unique[selector] = true
+	}
+	assert.Len(t, unique, storage.MaxReportInflation)
+
+	_, err = storage.InflateReport(report, storage.MaxReportInflation+1)
+	assert.Error(t, err)
+	_, err = storage.InflateReport(report, -1)
+	assert.Error(t, err)
+}
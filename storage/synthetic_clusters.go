@@ -0,0 +1,87 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"fmt"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// synthetic clusters are generated for one selected organization to get
+// large number of clusters without authoring report files
+// please note that these clusters have special name:
+// "{org ID in hex}-5555-5555-5555-{index}"
+//
+// Mnemotechnic: 5 - synthetic
+const syntheticClusterNameFormat = "%08x-5555-5555-5555-%012d"
+
+// syntheticClusters returns list of synthetic clusters generated for given
+// organization. The names are derived from organization ID, so they are the
+// same across restarts.
+func (storage MemoryStorage) syntheticClusters(orgID types.OrgID) []types.ClusterName {
+	count := storage.syntheticClustersCount
+	if orgID != storage.syntheticClustersOrgID || count <= 0 {
+		return nil
+	}
+
+	clusters := make([]types.ClusterName, count)
+	for i := range clusters {
+		clusters[i] = types.ClusterName(fmt.Sprintf(syntheticClusterNameFormat, uint32(orgID), i+1))
+	}
+	return clusters
+}
+
+// parseSyntheticCluster checks whether given cluster is synthetic cluster
+// and returns its organization and index
+func (storage MemoryStorage) parseSyntheticCluster(clusterName types.ClusterName) (types.OrgID, int, bool) {
+	syntheticOrgID, count := storage.syntheticClustersOrgID, storage.syntheticClustersCount
+	if count <= 0 {
+		return 0, 0, false
+	}
+
+	var orgID uint32
+	var index int
+	_, err := fmt.Sscanf(string(clusterName), syntheticClusterNameFormat, &orgID, &index)
+	if err != nil || types.OrgID(orgID) != syntheticOrgID || index < 1 || index > count {
+		return 0, 0, false
+	}
+
+	// Sscanf accepts shorter numbers, so the name is checked as a whole
+	if string(clusterName) != fmt.Sprintf(syntheticClusterNameFormat, orgID, index) {
+		return 0, 0, false
+	}
+
+	return types.OrgID(orgID), index, true
+}
+
+// syntheticClusterReportName returns name of cluster whose report is used
+// for given synthetic cluster. Reports of real clusters of the organization
+// are used in round-robin fashion.
+func (storage MemoryStorage) syntheticClusterReportName(clusterName types.ClusterName) (types.ClusterName, bool) {
+	orgID, index, ok := storage.parseSyntheticCluster(clusterName)
+	if !ok {
+		return "", false
+	}
+
+	realClusters := loadedOrganizations()[orgID]
+	if len(realClusters) == 0 {
+		return "", false
+	}
+
+	return realClusters[(index-1)%len(realClusters)], true
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/RedHatInsights/insights-results-aggregator-mock/types"
+)
+
+// Report templates are stored in files named report_template_{prefix}.json.
+// Such template is used for all clusters with name starting with {prefix}
+// that don't have their own report file. When more templates match the
+// cluster name, the one with the longest prefix is used.
+const (
+	reportTemplateFilePrefix = "report_template_"
+	reportTemplateFileSuffix = ".json"
+)
+
+// ReportTemplateContext contains values that can be used in report templates
+// as placeholders, for example {{.ClusterName}} or {{.Now}}
+type ReportTemplateContext struct {
+	ClusterName types.ClusterName
+	Now         string
+}
+
+// parsed report templates, key is cluster name prefix
+var reportTemplates map[string]*template.Template = make(map[string]*template.Template)
+
+// loadedReportTemplates returns map with all currently loaded report
+// templates
+func loadedReportTemplates() map[string]*template.Template {
+	reportsMutex.RLock()
+	defer reportsMutex.RUnlock()
+	return reportTemplates
+}
+
+// loadReportTemplates reads and parses all report templates found in given
+// directory (or the embedded dataset)
+func loadReportTemplates(path string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	fsys := dataFiles(path)
+
+	files, err := fs.Glob(fsys, reportTemplateFilePrefix+"*"+reportTemplateFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		prefix := strings.TrimSuffix(strings.TrimPrefix(file, reportTemplateFilePrefix), reportTemplateFileSuffix)
+
+		tmpl, err := template.ParseFS(fsys, file)
+		if err != nil {
+			log.Error().Err(err).Str("file", file).Msg("Unable to parse report template")
+			return nil, err
+		}
+
+		log.Info().Str("file", file).Str("prefix", prefix).Msg("Report template loaded")
+		templates[prefix] = tmpl
+	}
+
+	return templates, nil
+}
+
+// findReportTemplate tries to find report template for given cluster
+func findReportTemplate(clusterName types.ClusterName) (*template.Template, bool) {
+	var found *template.Template
+	longestPrefix := -1
+
+	for prefix, tmpl := range loadedReportTemplates() {
+		if strings.HasPrefix(string(clusterName), prefix) && len(prefix) > longestPrefix {
+			found = tmpl
+			longestPrefix = len(prefix)
+		}
+	}
+
+	return found, found != nil
+}
+
+// renderReportTemplate executes report template for given cluster at given
+// time. Error is returned when the rendered report is not valid JSON.
+func renderReportTemplate(tmpl *template.Template, clusterName types.ClusterName, now time.Time) (string, error) {
+	context := ReportTemplateContext{
+		ClusterName: clusterName,
+		Now:         now.UTC().Format(time.RFC3339),
+	}
+
+	var rendered bytes.Buffer
+	err := tmpl.Execute(&rendered, context)
+	if err != nil {
+		return "", fmt.Errorf("unable to render report template %s: %v", tmpl.Name(), err)
+	}
+	if !json.Valid(rendered.Bytes()) {
+		return "", fmt.Errorf("report template %s rendered invalid JSON for cluster %s", tmpl.Name(), clusterName)
+	}
+
+	return rendered.String(), nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+)
+
+// maximum length of source line shown in validation error
+const maxErrorContextLength = 80
+
+// Validate checks that all report files (report_*.json) stored in given
+// directory or tar.gz archive can be read and parsed as JSON. The service
+// does not need to be started. One error is returned for each improper file,
+// containing the first parse error found together with line of the file where
+// it occurred. Report templates are not checked.
+func Validate(path string) []error {
+	entries, err := readReportFiles(path)
+	if err != nil {
+		return []error{err}
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	validationErrors := []error{}
+	for _, name := range names {
+		var parsed interface{}
+		err := json.Unmarshal(entries[name], &parsed)
+		if err != nil {
+			validationErrors = append(validationErrors, describeParseError(name, entries[name], err))
+		}
+	}
+	return validationErrors
+}
+
+// readReportFiles reads content of all report files stored in given directory
+// or tar.gz archive. Embedded dataset is read when the path is not set.
+func readReportFiles(path string) (map[string][]byte, error) {
+	if isArchive(path) {
+		entries, err := readArchive(path)
+		if err != nil {
+			return nil, err
+		}
+		for name := range entries {
+			if strings.HasPrefix(name, reportTemplateFilePrefix) {
+				delete(entries, name)
+			}
+		}
+		return entries, nil
+	}
+
+	// unlike the service itself, the validation must not fall back to
+	// embedded dataset for inaccessible directory
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+	}
+
+	files := dataFiles(path)
+	names, err := fs.Glob(files, archiveReportEntryMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string][]byte, len(names))
+	for _, name := range names {
+		if strings.HasPrefix(name, reportTemplateFilePrefix) {
+			continue
+		}
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+		entries[name] = content
+	}
+	return entries, nil
+}
+
+// describeParseError adds file name, line and column and content of the line
+// to JSON parse error
+func describeParseError(name string, content []byte, err error) error {
+	var offset int64 = -1
+
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxError):
+		offset = syntaxError.Offset
+	case errors.As(err, &typeError):
+		offset = typeError.Offset
+	}
+
+	if offset < 0 {
+		// error without position, for example unexpected end of input
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	line, column, text := lineAtOffset(content, offset)
+	return fmt.Errorf("%s:%d:%d: %v: %q", name, line, column, err, text)
+}
+
+// lineAtOffset returns line and column numbers (starting from 1) and content
+// of line for given offset in content
+func lineAtOffset(content []byte, offset int64) (int, int, string) {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	column := int(offset) - lineStart
+
+	lineEnd := bytes.IndexByte(content[lineStart:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(content) - lineStart
+	}
+
+	text := strings.TrimSpace(string(content[lineStart : lineStart+lineEnd]))
+	if len(text) > maxErrorContextLength {
+		text = text[:maxErrorContextLength] + "..."
+	}
+	return line, column, text
+}
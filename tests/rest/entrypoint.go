@@ -20,6 +20,8 @@ limitations under the License.
 package tests
 
 import (
+	"fmt"
+
 	"github.com/verdverm/frisby"
 )
 
@@ -37,7 +39,12 @@ func checkNonExistentEntryPoint() {
 	f := frisby.Create("Check the non-existent entry point to REST API").Get(apiURL + "foobar")
 	f.Send()
 	f.ExpectStatus(404)
-	f.ExpectHeader(contentTypeHeader, ContentTypeText)
+	f.ExpectHeader(contentTypeHeader, ContentTypeJSON)
+
+	response := readStatusFromResponse(f)
+	if response.Status != "Not Found" {
+		f.AddError(fmt.Sprintf("Expected status is 'Not Found', but got '%s' instead", response.Status))
+	}
 	f.PrintReport()
 }
 
@@ -48,7 +55,7 @@ func checkWrongEntryPoint() {
 		f := frisby.Create("Check the wrong entry point to REST API with postfix '" + postfix + "'").Get(apiURL + postfix)
 		f.Send()
 		f.ExpectStatus(404)
-		f.ExpectHeader(contentTypeHeader, ContentTypeText)
+		f.ExpectHeader(contentTypeHeader, ContentTypeJSON)
 		f.PrintReport()
 	}
 }
@@ -0,0 +1,53 @@
+/*
+Copyright © 2020 Red Hat, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tests
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/verdverm/frisby"
+)
+
+const knownClusterReportURL = apiURL + "report/34c3ecc5-624a-49a5-bab8-4fdc5e51a266"
+
+// checkHeadRequestForReport checks that HEAD request returns the same
+// Content-Length as GET request for the same report, but without body
+func checkHeadRequestForReport() {
+	f := frisby.Create("Check reading report by using HTTP GET method").Get(knownClusterReportURL)
+	f.Send()
+	f.ExpectStatus(200)
+
+	body, err := f.Resp.Content()
+	if err != nil {
+		f.AddError(err.Error())
+	}
+	f.PrintReport()
+
+	f = frisby.Create("Check reading report size by using HTTP HEAD method").Head(knownClusterReportURL)
+	f.Send()
+	f.ExpectStatus(200)
+	f.ExpectHeader(contentLengthHeader, strconv.Itoa(len(body)))
+
+	headBody, err := f.Resp.Content()
+	if err != nil {
+		f.AddError(err.Error())
+	} else if len(headBody) != 0 {
+		f.AddError(fmt.Sprintf("Expected empty body for HEAD request, but got %d bytes", len(headBody)))
+	}
+	f.PrintReport()
+}
@@ -22,6 +22,7 @@ package tests
 // ServerTests run all tests for basic REST API endpoints
 func ServerTests() {
 	BasicTests()
+	ReportTests()
 }
 
 // BasicTests implements basic tests for REST API apiPrefix
@@ -32,3 +33,9 @@ func BasicTests() {
 	checkWrongEntryPoint()
 	checkWrongMethodsForEntryPoint()
 }
+
+// ReportTests implements tests for REST API endpoints returning reports
+func ReportTests() {
+	// implementation of these tests is stored in report.go
+	checkHeadRequestForReport()
+}